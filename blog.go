@@ -21,11 +21,14 @@ Usage:
 	// Log messages with formatting
 	blog.Warnf("This is an warn message with a format string: %v", err)
 
+	// Log messages with structured fields, as key/value pairs or typed fields
+	blog.Info("user logged in", "user", id, blog.String("ip", addr))
+
 	// Synchronously cleanup the logger with a timeout; 0 means block indefinitely.
 	// This should be called at the end of the program.
 	blog.Cleanup(0)
 
-	// for all other functions see `blog.go`. For access to the raw logger, see the other internal packages.
+	// for all other functions see `blog.go`. For independent instances, see `blog.New`.
 
 # Performance Notes
 
@@ -36,13 +39,20 @@ Defaults; All of these are modifiable at runtime via the public functions:
 
 A single thread is used to handle all logging operations.
 The channel that feeds it messages is buffered to 255 in the instance managed by the public functions.
-If you need control over it, you can create your own instance of the raw logger. Note interfacing with
-the raw logger is is different from the simplified public functions.
+If you need control over it, or more than one logger, create your own instance with New:
+
+	l, err := blog.New(blog.Options{DirectoryPath: "logs/db", Level: blog.DEBUG, MsgChanSize: 1024})
+	if err != nil {
+		log.Printf("Error creating logger: %v", err)
+	}
+	defer l.Cleanup(0)
+	l.Info("connected", "host", host)
 
 # For contributors
 
 The approach is pretty straightforward. There is a slightly lower abstraction level logger in the logger package.
-This file creates and manages an instance of it for the common use case of a high abstraction singleton logger.
+The Logger type in `logger.go` wraps it in the public API, and this file manages an instance of that for the
+common use case of a high abstraction singleton logger.
 
 The logger is a struct with a few channels for communication and vars for configuration.
 When created it starts a goroutine that listens for messages/config updates via the chans then handles them.
@@ -56,25 +66,39 @@ This has some nice benefits:
 package blog
 
 import (
+	"encoding"
+	"encoding/json"
+	"flag"
 	"fmt"
-	"log"
-	"os"
+	"io"
+	"log/slog"
 	"time"
 
 	"github.com/Data-Corruption/blog/v3/internal/config"
+	"github.com/Data-Corruption/blog/v3/internal/format"
 	LogLevel "github.com/Data-Corruption/blog/v3/internal/level"
 	"github.com/Data-Corruption/blog/v3/internal/logger"
-	"github.com/Data-Corruption/blog/v3/internal/utils"
+	"github.com/Data-Corruption/blog/v3/internal/record"
+	"github.com/Data-Corruption/blog/v3/internal/sink"
 )
 
 var (
 	ErrAlreadyInitialized = fmt.Errorf("blog: already initialized")
 	ErrInvalidLogLevel    = fmt.Errorf("blog: invalid log level")
+	ErrInvalidVModule     = fmt.Errorf("blog: invalid vmodule")
+	ErrInvalidLayout      = fmt.Errorf("blog: invalid text layout")
 	ErrUninitialized      = fmt.Errorf("blog: uninitialized")
 	ErrShutdown           = fmt.Errorf("blog: logger has been shut down")
-	ErrInvalidPath        = fmt.Errorf("blog: invalid path")
 
-	instance *logger.Logger = nil
+	// Errors passed to the SetErrorHandler function wrap one of these, test for them with errors.Is.
+	ErrInvalidPath = logger.ErrInvalidPath // the log directory doesn't exist or isn't a directory.
+	ErrWrite       = logger.ErrWrite       // writing the log file failed, file logging fell back to the console.
+	ErrRotate      = logger.ErrRotate      // rotating the log file failed, file logging fell back to the console.
+	ErrCompress    = logger.ErrCompress    // compressing a rotated file failed, the uncompressed file is kept.
+	ErrRetention   = logger.ErrRetention   // deleting old rotated files failed.
+	ErrSink        = logger.ErrSink        // a sink failed to write, flush or close.
+
+	instance *Logger = nil
 )
 
 // Init sets up the logger with the specified configuration parameters.
@@ -97,122 +121,225 @@ func Init(
 	if instance != nil {
 		return ErrAlreadyInitialized
 	}
-	pathCopy := DirPath
-	levelCopy := LogLevel.LogLevel(Level)
-	location := utils.Ternary(IncludeLocation, 5, -1)
-	cout := utils.Ternary(EnableConsole, &config.ConsoleLogger{L: log.New(os.Stdout, "", 0)}, nil)
 	var err error
-	instance, err = logger.NewLogger(&config.Config{Level: &levelCopy, DirectoryPath: &pathCopy, ConsoleOut: cout}, 255, location)
+	instance, err = New(Options{
+		DirectoryPath:   DirPath,
+		Level:           Level,
+		IncludeLocation: IncludeLocation,
+		LocationSkip:    1, // the package level function wrapping each method
+		EnableConsole:   EnableConsole,
+	})
 	return err
 }
 
-// Cleanup flushes the log write buffer and exits the logger. If timeout is 0, Cleanup blocks indefinitely.
-func Cleanup(timeout time.Duration) error {
-	return a(func() { time.Sleep(20 * time.Millisecond); instance.Shutdown(timeout) })
-}
+// Cleanup writes out every message logged so far, flushes the log write buffer and exits the logger.
+// If timeout is 0, Cleanup blocks indefinitely. Otherwise messages not written in time are lost and
+// the returned error says how many. If the logger is too busy to start stopping in time, it keeps
+// running and the error says so, call Cleanup again.
+func Cleanup(timeout time.Duration) error { return instance.Cleanup(timeout) }
+
+// With returns a child Logger of the blog instance that adds the given fields to every message.
+// Example: reqLog := blog.With("request_id", id); reqLog.Info("handled")
+func With(fields ...any) *Logger { return instance.With(fields...) }
+
+// Named returns a child Logger of the blog instance that stamps every message with a component name.
+func Named(name string) *Logger { return instance.Named(name) }
 
 // ==== Logging Functions ===
+//
+// The non-format functions accept optional structured fields, either as Field values or as alternating
+// key/value pairs. Example: blog.Info("login", "user", id, blog.String("ip", addr))
+
+func Error(msg string, fields ...any) error   { return instance.Error(msg, fields...) }
+func Errorf(format string, args ...any) error { return instance.Errorf(format, args...) }
+func Warn(msg string, fields ...any) error    { return instance.Warn(msg, fields...) }
+func Warnf(format string, args ...any) error  { return instance.Warnf(format, args...) }
+func Info(msg string, fields ...any) error    { return instance.Info(msg, fields...) }
+func Infof(format string, args ...any) error  { return instance.Infof(format, args...) }
+func Debug(msg string, fields ...any) error   { return instance.Debug(msg, fields...) }
+func Debugf(format string, args ...any) error { return instance.Debugf(format, args...) }
+func Trace(msg string, fields ...any) error   { return instance.Trace(msg, fields...) }
+func Tracef(format string, args ...any) error { return instance.Tracef(format, args...) }
+
+// Log logs a message at the given level, e.g. one added with RegisterLevel. A FATAL message is only
+// logged, use Fatal to exit. NONE is only a threshold, logging with it returns ErrInvalidLogLevel.
+func Log(level Level, msg string, fields ...any) error { return instance.Log(level, msg, fields...) }
+func Logf(level Level, format string, args ...any) error {
+	return instance.Logf(level, format, args...)
+}
 
-func Error(msg string) error                  { return a(func() { instance.Error(msg) }) }
-func Errorf(format string, args ...any) error { return a(func() { instance.Errorf(format, args...) }) }
-func Warn(msg string) error                   { return a(func() { instance.Warn(msg) }) }
-func Warnf(format string, args ...any) error  { return a(func() { instance.Warnf(format, args...) }) }
-func Info(msg string) error                   { return a(func() { instance.Info(msg) }) }
-func Infof(format string, args ...any) error  { return a(func() { instance.Infof(format, args...) }) }
-func Debug(msg string) error                  { return a(func() { instance.Debug(msg) }) }
-func Debugf(format string, args ...any) error { return a(func() { instance.Debugf(format, args...) }) }
-
-// Fatal logs a fatal message and exits with the given exit code.
-// This function will not return, it will exit the program after attempting to log the message.
-func Fatal(exitCode int, timeout time.Duration, msg string) error {
-	return a(func() { instance.Fatal(exitCode, timeout, msg) })
+// Fatal logs a fatal message and exits with the given exit code as soon as the message is flushed, or
+// once the timeout passes. A timeout of 0 waits indefinitely. Exit hooks run first, see RegisterExitHook.
+// This function will not return unless the logger is uninitialized or shut down.
+func Fatal(exitCode int, timeout time.Duration, msg string, fields ...any) error {
+	return instance.Fatal(exitCode, timeout, msg, fields...)
 }
 
-// Fatalf logs a fatal message with a format string and exits with the given exit code.
-// This function will not return, it will exit the program after attempting to log the message.
+// Fatalf logs a fatal message with a format string and exits like Fatal.
 func Fatalf(exitCode int, timeout time.Duration, format string, args ...any) error {
-	return a(func() { instance.Fatalf(exitCode, timeout, format, args...) })
+	return instance.Fatalf(exitCode, timeout, format, args...)
 }
 
+// RegisterExitHook adds a function for Fatal to run before exiting, e.g. to flush other loggers or close
+// connections. Hooks run once, in registration order, even when several loggers call Fatal at once.
+func RegisterExitHook(f func()) { logger.RegisterExitHook(f) }
+
+// Enabled reports whether a message of the given level would currently be logged.
+// Messages below the level are already discarded cheaply, this is for guarding expensive arguments:
+//
+//	if blog.Enabled(blog.DEBUG) {
+//		blog.Debug("state", "dump", expensiveDump())
+//	}
+func Enabled(level Level) bool { return instance.Enabled(level) }
+
+// SlogHandler returns a slog.Handler that writes through blog, so log/slog users get the same buffering,
+// rotation and outputs. slog levels map directly onto blog levels, groups become dotted field keys.
+//
+//	h, err := blog.SlogHandler()
+//	if err == nil {
+//		slog.SetDefault(slog.New(h))
+//	}
+func SlogHandler() (slog.Handler, error) { return instance.SlogHandler() }
+
 // SetLevel sets the log level.
-func SetLevel(level Level) error {
-	l_level := LogLevel.LogLevel(level)
-	return a(func() { instance.UpdateConfig(config.Config{Level: &l_level}) })
-}
+func SetLevel(level Level) error { return instance.SetLevel(level) }
+
+// SetVModule overrides the level for some components or source files, e.g. "db=DEBUG,http/*=WARN"
+// enables DEBUG for the logger named "db" and limits files in http directories to WARN and above.
+// See Logger.SetVModule for the matching rules. An empty spec removes the overrides.
+func SetVModule(spec string) error { return instance.SetVModule(spec) }
 
 // SetConsole enables or disables console logging.
-func SetConsole(enable bool) error {
-	cl := &config.ConsoleLogger{L: utils.Ternary(enable, log.New(os.Stdout, "", 0), nil)}
-	return a(func() { instance.UpdateConfig(config.Config{ConsoleOut: cl}) })
-}
+func SetConsole(enable bool) error { return instance.SetConsole(enable) }
+
+// SetConsoleLevel sets the minimum level printed to the console. It filters on top of SetLevel,
+// e.g. SetLevel(DEBUG) with SetFileLevel(WARN) gives DEBUG on the console and WARN+ in the file.
+func SetConsoleLevel(level Level) error { return instance.SetConsoleLevel(level) }
+
+// SetConsoleEncoding sets the encoding of console output. TextEncoding is the default.
+func SetConsoleEncoding(enc Encoding) error { return instance.SetConsoleEncoding(enc) }
+
+// SetTextLayout sets the layout of text output to the file and console, e.g. to show UTC times with
+// milliseconds and lower case levels first:
+//
+//	blog.SetTextLayout(blog.TextLayout{
+//		Template:   "{level} {time} {name}{message}{fields}",
+//		TimeLayout: time.RFC3339,
+//		UTC:        true,
+//		Precision:  3,
+//		LevelCase:  blog.LevelLowerCase,
+//		LevelWidth: 5,
+//	})
+//
+// See TextLayout for the placeholders. Returns an error wrapping ErrInvalidLayout if it is invalid.
+func SetTextLayout(layout TextLayout) error { return instance.SetTextLayout(layout) }
 
 // ==== Buffer controls ====
 
 // Flush manually flushes the log write buffer.
-func Flush() error { return a(func() { instance.Flush() }) }
+func Flush() error { return instance.Flush() }
 
 // SyncFlush synchronously flushes the log write buffer and blocks until the flush is complete or the
 // timeout is reached. If timeout is 0, SyncFlush blocks indefinitely.
-func SyncFlush(timeout time.Duration) error { return a(func() { instance.SyncFlush(timeout) }) }
+func SyncFlush(timeout time.Duration) error { return instance.SyncFlush(timeout) }
+
+// Reopen flushes and closes the log file, and reopens it at its path on the next write. Call it after
+// moving the log file, or enable SetExternalRotation to have SIGHUP do the same.
+func Reopen() error { return instance.Reopen() }
 
 // SetMaxBufferSizeBytes sets the maximum size of the log write buffer. Larger values will increase memory
 // usage and reduce the frequency of disk writes.
-func SetMaxBufferSizeBytes(size int) error {
-	return a(func() { instance.UpdateConfig(config.Config{MaxBufferSizeBytes: &size}) })
+func SetMaxBufferSizeBytes(size int) error { return instance.SetMaxBufferSizeBytes(size) }
+
+// SetOverflowPolicy sets what happens to messages logged while the message channel is full, e.g. when
+// a slow disk backs it up. OverflowBlock (the default) waits for room, the others drop messages rather
+// than stall the caller. Level is only used by OverflowDropBelow. FATAL messages are never dropped, and
+// a "N messages dropped" record is logged once the channel has drained.
+func SetOverflowPolicy(policy OverflowPolicy, level Level) error {
+	return instance.SetOverflowPolicy(policy, level)
 }
 
+// Dropped returns the number of messages dropped by the overflow policy so far.
+func Dropped() uint64 { return instance.Dropped() }
+
 // SetFlushInterval sets the interval at which the log write buffer is automatically flushed to the log file.
 // This happens regardless of the buffer size. A value of 0 disables automatic flushing.
-func SetFlushInterval(d time.Duration) error {
-	return a(func() { instance.UpdateConfig(config.Config{FlushInterval: &d}) })
-}
+func SetFlushInterval(d time.Duration) error { return instance.SetFlushInterval(d) }
 
 // ==== File controls ====
 
-// SetMaxFileSizeBytes sets the maximum size of the log file. When the log file reaches
-// this size, it is renamed to the current timestamp and a new log file is created.
-func SetMaxFileSizeBytes(size int) error {
-	return a(func() { instance.UpdateConfig(config.Config{MaxFileSizeBytes: &size}) })
-}
+// SetMaxFileSizeBytes sets the maximum size of the log file. When the next record would take the
+// log file over this size, it is renamed to the current timestamp and a new log file is created.
+// Records are never split, so a single record larger than the limit gets a file of its own.
+func SetMaxFileSizeBytes(size int) error { return instance.SetMaxFileSizeBytes(size) }
+
+// SetRotationSchedule sets when the log file is rotated based on time, in addition to its size.
+// Rotated files are named for the start of the period they cover. Example: blog.SetRotationSchedule(blog.RotateHourly())
+func SetRotationSchedule(s RotationSchedule) error { return instance.SetRotationSchedule(s) }
+
+// SetRetention sets the limits on the rotated log files kept, e.g. blog.RetentionPolicy{MaxFiles: 10, MaxAge: 7 * 24 * time.Hour}.
+// The oldest rotated files are deleted at startup and after each rotation until every limit is met.
+// Only files matching blog's rotated naming pattern are ever deleted.
+func SetRetention(p RetentionPolicy) error { return instance.SetRetention(p) }
+
+// SetCompression enables or disables gzip compression of rotated log files. Compression runs in the
+// background, writing "<name>.log.gz" and removing the original only once the compressed file is complete.
+func SetCompression(enable bool) error { return instance.SetCompression(enable) }
+
+// SetExternalRotation hands rotation over to an external tool like logrotate. While enabled, blog
+// ignores the size limit and rotation schedule and reopens the log file when the process receives
+// SIGHUP, so the tool can move the file with "create" and signal, or use "copytruncate".
+func SetExternalRotation(enable bool) error { return instance.SetExternalRotation(enable) }
+
+// SetFileNaming sets the names of the active and rotated log files, e.g. blog.FileNaming{Prefix: "api-"}
+// for services sharing a directory. Retention only deletes files matching the current naming.
+func SetFileNaming(n FileNaming) error { return instance.SetFileNaming(n) }
+
+// SetRecovery sets how file logging is retried after a write or rotate failure made the logger fall
+// back to the console, e.g. blog.RecoveryPolicy{InitialDelay: time.Second, MaxDelay: time.Minute}.
+// Once the directory can be written again, file logging resumes with a record of the outage.
+func SetRecovery(p RecoveryPolicy) error { return instance.SetRecovery(p) }
 
 // SetDirectoryPath sets the directory path for the log files. To disable file logging, use an empty string.
-func SetDirectoryPath(path string) error {
-	return a(func() { instance.UpdateConfig(config.Config{DirectoryPath: &path}) })
-}
+func SetDirectoryPath(path string) error { return instance.SetDirectoryPath(path) }
 
-// === helpers ===
+// SetFileEncoding sets the encoding of records written to the log file. TextEncoding is the default,
+// JSONEncoding writes one JSON object per line.
+func SetFileEncoding(enc Encoding) error { return instance.SetFileEncoding(enc) }
 
-// instanceGuard is a helper function that checks if the logger instance is initialized and not shutdown.
-func instanceGuard() error {
-	if instance == nil {
-		return ErrUninitialized
-	}
-	instance.RunningMutex.Lock()
-	running := instance.Running
-	instance.RunningMutex.Unlock()
-	return utils.Ternary(running, nil, ErrShutdown)
-}
+// SetFileLevel sets the minimum level written to the log file. It filters on top of SetLevel.
+func SetFileLevel(level Level) error { return instance.SetFileLevel(level) }
 
-// a is a helper function for methods that don't return anything.
-func a(f func()) error {
-	if err := instanceGuard(); err != nil {
-		return err
-	}
-	f()
-	return nil
-}
+// ==== Sink controls ====
+
+// SetSinks replaces the additional sinks with the given ones. Sinks no longer present are closed.
+// Call it with no arguments to remove all additional sinks. The file and console outputs are unaffected.
+func SetSinks(sinks ...Sink) error { return instance.SetSinks(sinks...) }
+
+// ==== Error controls ====
+
+// SetErrorHandler sets a function called with the logger's internal errors, on top of printing them,
+// so monitoring can alert when the logger itself is degraded. Errors wrap ErrInvalidPath, ErrWrite,
+// ErrRotate, ErrCompress, ErrRetention or ErrSink. The function is called from the logger's goroutine,
+// so it should return quickly and must not wait on the logger, e.g. with SyncFlush. Nil removes it.
+func SetErrorHandler(f func(error)) error { return instance.SetErrorHandler(f) }
 
 // Re-exported for convenience / unified API.
 
+// Level is the severity of a message, and the threshold SetLevel and friends filter with: a threshold
+// lets through messages at least as severe as itself. FATAL is the most severe, so it passes any
+// threshold but NONE. The zero value is INFO. Values match log/slog's and leave room for custom
+// levels in between, see RegisterLevel.
 type Level int
 
 const (
-	NONE Level = iota
-	ERROR
-	WARN
-	INFO
-	DEBUG
-	FATAL
+	TRACE Level = Level(LogLevel.TRACE)
+	DEBUG Level = Level(LogLevel.DEBUG)
+	INFO  Level = Level(LogLevel.INFO)
+	WARN  Level = Level(LogLevel.WARN)
+	ERROR Level = Level(LogLevel.ERROR)
+	FATAL Level = Level(LogLevel.FATAL)
+	NONE  Level = Level(LogLevel.NONE) // a threshold that lets nothing through.
 )
 
 // String returns the string representation of a blog.Level
@@ -220,7 +347,20 @@ func (l Level) String() string {
 	return LogLevel.LogLevel(l).String()
 }
 
+// RegisterLevel adds a named level with the given severity, e.g. RegisterLevel("NOTICE", INFO+2) for a
+// level between INFO and WARN. It is then filtered by severity, shown by name in every encoding and
+// accepted by FromString. Names are case-insensitive, and neither the name nor the severity may already
+// be in use, though registering the same level again does nothing. Levels are process wide, register
+// them once at startup. Returns ErrInvalidLogLevel.
+func RegisterLevel(name string, severity Level) error {
+	if err := LogLevel.Register(name, LogLevel.LogLevel(severity)); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidLogLevel, err)
+	}
+	return nil
+}
+
 // FromString sets a blog.Level from a case-insensitive string, returning ErrInvalidLogLevel if the string is invalid.
+// Registered names, offsets as written by String, e.g. "INFO+2", and numeric severities, e.g. "4", are accepted too.
 func (l *Level) FromString(levelStr string) error {
 	ll := LogLevel.LogLevel(*l)
 	if err := ll.FromString(levelStr); err != nil {
@@ -229,3 +369,152 @@ func (l *Level) FromString(levelStr string) error {
 	*l = Level(ll)
 	return nil
 }
+
+// Level can be used directly as a flag, e.g. flag.Var(&lvl, "log-level", "minimum level logged"), and in
+// config structs decoded from JSON, or from YAML and the like through the text interfaces. It is written as
+// its name, and read from a name or a numeric severity.
+var (
+	_ flag.Value               = (*Level)(nil)
+	_ encoding.TextMarshaler   = Level(0)
+	_ encoding.TextUnmarshaler = (*Level)(nil)
+	_ json.Marshaler           = Level(0)
+	_ json.Unmarshaler         = (*Level)(nil)
+)
+
+// Set implements flag.Value, see FromString.
+func (l *Level) Set(levelStr string) error { return l.FromString(levelStr) }
+
+// MarshalText implements encoding.TextMarshaler, writing the level's name.
+func (l Level) MarshalText() ([]byte, error) { return []byte(l.String()), nil }
+
+// UnmarshalText implements encoding.TextUnmarshaler, see FromString.
+func (l *Level) UnmarshalText(text []byte) error { return l.FromString(string(text)) }
+
+// MarshalJSON implements json.Marshaler, writing the level's name as a JSON string.
+func (l Level) MarshalJSON() ([]byte, error) { return json.Marshal(l.String()) }
+
+// UnmarshalJSON implements json.Unmarshaler, accepting a name or numeric severity as a string, or a
+// JSON number. null leaves the level unchanged.
+func (l *Level) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var levelStr string
+	if err := json.Unmarshal(data, &levelStr); err != nil {
+		levelStr = string(data) // a number, anything else is rejected by FromString
+	}
+	return l.FromString(levelStr)
+}
+
+// Encoding selects how records are written to the log file, console and writer sinks.
+type Encoding = format.Encoding
+
+const (
+	TextEncoding Encoding = format.Text // e.g. "[2006-01-02,15-04-05,INFO]  message key=value"
+	JSONEncoding Encoding = format.JSON // JSON Lines, e.g. {"time":"...","level":"INFO","message":"message","fields":{"key":"value"}}
+)
+
+// TextLayout describes the layout of TextEncoding lines, see SetTextLayout. The zero TextLayout is the
+// default, DefaultTextTemplate with times like "2006-01-02,15-04-05". Template placeholders:
+//   - {time}     the time, see TimeLayout, UTC and Precision.
+//   - {level}    the level name, see LevelCase and LevelWidth.
+//   - {name}     the logger name as "[db] ", nothing for the root logger.
+//   - {location} the caller as "[main.go:42] ", nothing when locations are off.
+//   - {message}  the message, required.
+//   - {fields}   the fields as " key=value" each, values quoted when needed.
+//   - {pad=N}    pads the line so far with spaces to N characters.
+type TextLayout = format.LayoutOptions
+
+// DefaultTextTemplate is the template of the default text layout,
+// e.g. "[2006-01-02,15-04-05,INFO]  [db] [main.go:42] login user=42".
+const DefaultTextTemplate = format.DefaultTemplate
+
+// LevelCase selects how level names are written by a TextLayout.
+type LevelCase = format.LevelCase
+
+const (
+	LevelUpperCase LevelCase = format.UpperCase // e.g. "WARN", the default.
+	LevelLowerCase LevelCase = format.LowerCase // e.g. "warn".
+	LevelTitleCase LevelCase = format.TitleCase // e.g. "Warn".
+)
+
+// OverflowPolicy decides what happens to a message logged while the message channel is full.
+type OverflowPolicy = config.OverflowPolicy
+
+const (
+	OverflowBlock      OverflowPolicy = config.Block      // wait for room, the default.
+	OverflowDropNewest OverflowPolicy = config.DropNewest // drop the message being logged.
+	OverflowDropOldest OverflowPolicy = config.DropOldest // drop the oldest queued message to make room.
+	OverflowDropBelow  OverflowPolicy = config.DropBelow  // drop messages below the given level, wait for the rest.
+)
+
+// RotationSchedule describes time based log rotation, see RotateHourly, RotateDaily and RotateEvery.
+// The zero value disables it.
+type RotationSchedule = config.Schedule
+
+// RotateHourly rotates the log file at the start of every hour.
+func RotateHourly() RotationSchedule { return RotationSchedule{Interval: time.Hour} }
+
+// RotateDaily rotates the log file every day at the given time past midnight, in UTC or local time.
+// Example: RotateDaily(3*time.Hour, true) rotates at 03:00 UTC.
+func RotateDaily(at time.Duration, utc bool) RotationSchedule {
+	return RotationSchedule{Interval: 24 * time.Hour, Offset: at, UTC: utc}
+}
+
+// RotateEvery rotates the log file every d, with periods counted from local midnight, or from
+// midnight on 1970-01-01 for intervals over a day that aren't whole days.
+func RotateEvery(d time.Duration) RotationSchedule { return RotationSchedule{Interval: d} }
+
+// RetentionPolicy limits how many rotated log files are kept. Zero fields are unlimited.
+type RetentionPolicy = config.Retention
+
+// FileNaming controls the names of log files. The zero FileNaming gives "latest.log" for the active
+// file and "2006-01-02_15-04-05.log" for rotated ones, with random suffixes on collisions.
+type FileNaming = config.Naming
+
+// RecoveryPolicy retries file logging with a doubling delay after a failure. The zero value never retries.
+type RecoveryPolicy = config.Recovery
+
+// Record is a single log message as handed to a Sink.
+type Record = record.Record
+
+// Sink is an output for log records, e.g. a network collector. Sinks are only called from the logger's
+// goroutine. The file and console outputs are built-in sinks, additional ones are added with SetSinks
+// or Options.Sinks.
+type Sink = sink.Sink
+
+// NewWriterSink returns a Sink that writes each record to w with the given encoding. w is never closed.
+// Text is written in the default layout, see NewWriterSinkWithLayout.
+func NewWriterSink(w io.Writer, enc Encoding) Sink { return sink.NewWriter(w, enc) }
+
+// NewWriterSinkWithLayout is NewWriterSink with the given layout for TextEncoding, which JSONEncoding
+// ignores. The sink keeps its layout, SetTextLayout only applies to the file and console.
+// Returns an error wrapping ErrInvalidLayout if it is invalid.
+func NewWriterSinkWithLayout(w io.Writer, enc Encoding, layout TextLayout) (Sink, error) {
+	l, err := format.NewLayout(layout)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidLayout, err)
+	}
+	return sink.NewWriterLayout(w, enc, &l), nil
+}
+
+// SinkWithLevel wraps s so it only receives records at the given level or more severe.
+// Example: blog.SetSinks(blog.SinkWithLevel(blog.NewWriterSink(conn, blog.JSONEncoding), blog.WARN))
+func SinkWithLevel(s Sink, level Level) Sink { return sink.WithLevel(s, LogLevel.LogLevel(level)) }
+
+// Field is a structured key/value pair attached to a log message.
+// In text output it is rendered as key=value after the message.
+type Field = record.Field
+
+// Field constructors, re-exported from the record package.
+
+func Any(key string, value any) Field                { return record.Any(key, value) }
+func String(key, value string) Field                 { return record.String(key, value) }
+func Int(key string, value int) Field                { return record.Int(key, value) }
+func Int64(key string, value int64) Field            { return record.Int64(key, value) }
+func Uint64(key string, value uint64) Field          { return record.Uint64(key, value) }
+func Float64(key string, value float64) Field        { return record.Float64(key, value) }
+func Bool(key string, value bool) Field              { return record.Bool(key, value) }
+func Time(key string, value time.Time) Field         { return record.Time(key, value) }
+func Duration(key string, value time.Duration) Field { return record.Duration(key, value) }
+func Err(err error) Field                            { return record.Err(err) }
@@ -2,8 +2,13 @@ package blog
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
 	"io"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -27,8 +32,11 @@ func TestConsoleLogging(t *testing.T) {
 	if err := Init("", INFO, false, true); err != nil {
 		t.Fatalf("Failed to initialize logger: %v", err)
 	}
-	// Ensure the logger is cleaned up after the test.
-	defer Cleanup(1 * time.Second)
+	// Ensure the logger is cleaned up after the test, and can be initialized again by a repeated run.
+	defer func() {
+		Cleanup(1 * time.Second)
+		instance = nil
+	}()
 
 	// Log a test message.
 	testMsg := "Hello, stdout logging!"
@@ -128,3 +136,294 @@ func TestLevelFromString(t *testing.T) {
 		}
 	}
 }
+
+func TestNewLoggers(t *testing.T) {
+	t.Parallel()
+	var bufA, bufB bytes.Buffer
+	a, err := New(Options{Level: DEBUG, EnableConsole: true, ConsoleWriter: &bufA, IncludeLocation: true})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	b, err := New(Options{Level: WARN, EnableConsole: true, ConsoleWriter: &bufB})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	a.Debug("debug to a")
+	b.Info("info to b") // filtered by b's level only
+	b.Warn("warn to b")
+	if err := a.Cleanup(time.Second); err != nil {
+		t.Errorf("Error cleaning up a: %v", err)
+	}
+	if err := b.Cleanup(time.Second); err != nil {
+		t.Errorf("Error cleaning up b: %v", err)
+	}
+
+	if out := bufA.String(); !strings.Contains(out, "[blog_test.go:") || !strings.Contains(out, "debug to a") {
+		t.Errorf("Expected a's output to contain the message and caller location, got %q", out)
+	}
+	if out := bufB.String(); strings.Contains(out, "info to b") || !strings.Contains(out, "warn to b") {
+		t.Errorf("Expected b's output to contain only the warn message, got %q", out)
+	}
+	if err := a.Info("after cleanup"); err != ErrShutdown {
+		t.Errorf("Expected ErrShutdown after cleanup, got %v", err)
+	}
+}
+
+func TestVModule(t *testing.T) {
+	t.Parallel()
+	if _, err := New(Options{VModule: "db"}); !errors.Is(err, ErrInvalidVModule) {
+		t.Errorf("Expected ErrInvalidVModule for a rule without a level, got %v", err)
+	}
+	var buf bytes.Buffer
+	l, err := New(Options{VModule: "blog_test.go=DEBUG", EnableConsole: true, ConsoleWriter: &buf})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	l.Debug("debug from this file") // the file override applies without IncludeLocation too
+	if err := l.SetVModule("db=DEBUG"); err != nil {
+		t.Fatalf("Failed to set vmodule: %v", err)
+	}
+	l.Debug("filtered debug")
+	l.Named("db").Debug("debug from db")
+	if err := l.SetVModule("db=LOUD"); !errors.Is(err, ErrInvalidVModule) {
+		t.Errorf("Expected ErrInvalidVModule for an invalid level, got %v", err)
+	}
+	if err := l.Cleanup(time.Second); err != nil {
+		t.Errorf("Error cleaning up: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "debug from this file") || !strings.Contains(out, "debug from db") || strings.Contains(out, "filtered debug") {
+		t.Errorf("Expected the overrides to decide the debug messages, got %q", out)
+	}
+}
+
+func TestTextLayout(t *testing.T) {
+	t.Parallel()
+	if _, err := New(Options{TextLayout: TextLayout{Template: "{time}"}}); !errors.Is(err, ErrInvalidLayout) {
+		t.Errorf("Expected ErrInvalidLayout for a template without {message}, got %v", err)
+	}
+	if _, err := NewWriterSinkWithLayout(io.Discard, TextEncoding, TextLayout{Template: "{time}"}); !errors.Is(err, ErrInvalidLayout) {
+		t.Errorf("Expected ErrInvalidLayout for a sink layout without {message}, got %v", err)
+	}
+	var buf, sinkBuf bytes.Buffer
+	s, err := NewWriterSinkWithLayout(&sinkBuf, TextEncoding, TextLayout{Template: "{message} <{level}>"})
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	l, err := New(Options{TextLayout: TextLayout{Template: "{level}: {message}", LevelCase: LevelTitleCase}, EnableConsole: true, ConsoleWriter: &buf, Sinks: []Sink{s}})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	l.Warn("from options")
+	time.Sleep(50 * time.Millisecond) // Allow the run loop to pick up the message before the new layout
+	if err := l.SetTextLayout(TextLayout{Template: "{message} ({level})", LevelWidth: 3}); err != nil {
+		t.Fatalf("Failed to set layout: %v", err)
+	}
+	l.Warn("from setter")
+	if err := l.SetTextLayout(TextLayout{Precision: -1}); !errors.Is(err, ErrInvalidLayout) {
+		t.Errorf("Expected ErrInvalidLayout for a negative precision, got %v", err)
+	}
+	if err := l.Cleanup(time.Second); err != nil {
+		t.Errorf("Error cleaning up: %v", err)
+	}
+	if out, expected := buf.String(), "Warn: from options\nfrom setter (WAR)\n"; out != expected {
+		t.Errorf("Expected %q, got %q", expected, out)
+	}
+	if out, expected := sinkBuf.String(), "from options <WARN>\nfrom setter <WARN>\n"; out != expected {
+		t.Errorf("Expected the sink to keep its own layout %q, got %q", expected, out)
+	}
+}
+
+func TestConsoleFallbackUsesConsoleWriter(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	l, err := New(Options{Level: DEBUG, ConsoleWriter: &buf}) // no directory, so the console is used
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	l.Error("e1")
+	if err := l.Cleanup(time.Second); err != nil {
+		t.Errorf("Error cleaning up: %v", err)
+	}
+	if !strings.Contains(buf.String(), "e1") {
+		t.Errorf("Expected the fallback console to write to ConsoleWriter, got %q", buf.String())
+	}
+}
+
+func TestOutputLevels(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	fileLevel, consoleLevel := WARN, INFO
+	l, err := New(Options{DirectoryPath: dir, Level: DEBUG, FileLevel: &fileLevel, ConsoleLevel: &consoleLevel, EnableConsole: true, ConsoleWriter: &buf})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	l.Debug("debug")
+	l.Info("info")
+	l.Warn("warn")
+	if err := l.Cleanup(time.Second); err != nil {
+		t.Errorf("Error cleaning up: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "latest.log"))
+	if err != nil {
+		t.Fatalf("Failed to read the log file: %v", err)
+	}
+	for out, expected := range map[string][]string{string(data): {"warn"}, buf.String(): {"info", "warn"}} {
+		var got []string
+		for _, msg := range []string{"debug", "info", "warn"} {
+			if strings.Contains(out, " "+msg+"\n") {
+				got = append(got, msg)
+			}
+		}
+		if strings.Join(got, ",") != strings.Join(expected, ",") {
+			t.Errorf("Expected %v in %q", expected, out)
+		}
+	}
+}
+
+func TestNewInvalidPath(t *testing.T) {
+	if _, err := New(Options{DirectoryPath: "does/not/exist"}); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("Expected ErrInvalidPath, got %v", err)
+	}
+}
+
+func TestCleanupDrainsQueuedMessages(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	l, err := New(Options{Level: INFO, EnableConsole: true, ConsoleWriter: &buf})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	for i := 0; i < 500; i++ {
+		l.Infof("message %d", i)
+	}
+	if err := l.Cleanup(time.Second); err != nil {
+		t.Errorf("Error cleaning up: %v", err)
+	}
+	if n := strings.Count(buf.String(), "message "); n != 500 {
+		t.Errorf("Expected all 500 messages to be written before Cleanup returned, got %d", n)
+	}
+}
+
+func TestLevelEncoding(t *testing.T) {
+	// Flags accept names and numeric severities.
+	var lvl Level
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Var(&lvl, "log-level", "minimum level logged")
+	if err := fs.Parse([]string{"-log-level", "debug"}); err != nil || lvl != DEBUG {
+		t.Errorf("Parse(debug) = %s, %v; expected DEBUG", lvl, err)
+	}
+	if err := fs.Parse([]string{"-log-level", "8"}); err != nil || lvl != ERROR {
+		t.Errorf("Parse(8) = %s, %v; expected ERROR", lvl, err)
+	}
+	if err := fs.Parse([]string{"-log-level", "loud"}); err == nil {
+		t.Errorf("Parse(loud) should fail")
+	}
+
+	// JSON writes names and reads names or numbers, through text too.
+	type cfg struct {
+		Level  Level   `json:"level"`
+		Levels []Level `json:"levels"`
+	}
+	data, err := json.Marshal(cfg{Level: WARN, Levels: []Level{INFO + 2, NONE}})
+	if err != nil || string(data) != `{"level":"WARN","levels":["INFO+2","NONE"]}` {
+		t.Errorf("Marshal = %s, %v", data, err)
+	}
+	var decoded cfg
+	if err := json.Unmarshal([]byte(`{"level":-4,"levels":["fatal","4",null]}`), &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if decoded.Level != DEBUG || len(decoded.Levels) != 3 || decoded.Levels[0] != FATAL || decoded.Levels[1] != WARN || decoded.Levels[2] != INFO {
+		t.Errorf("Unmarshal = %+v", decoded)
+	}
+	for _, invalid := range []string{`{"level":"loud"}`, `{"level":true}`, `{"level":4.5}`} {
+		if err := json.Unmarshal([]byte(invalid), &decoded); !errors.Is(err, ErrInvalidLogLevel) {
+			t.Errorf("Unmarshal(%s) = %v; expected ErrInvalidLogLevel", invalid, err)
+		}
+	}
+	text, err := TRACE.MarshalText()
+	if err != nil || string(text) != "TRACE" {
+		t.Errorf("MarshalText = %s, %v", text, err)
+	}
+	if err := lvl.UnmarshalText(text); err != nil || lvl != TRACE {
+		t.Errorf("UnmarshalText(%s) = %s, %v", text, lvl, err)
+	}
+}
+
+func TestLevelSeverity(t *testing.T) {
+	t.Parallel()
+	l, err := New(Options{Level: INFO})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	defer l.Cleanup(time.Second)
+
+	for _, tt := range []struct {
+		level Level
+		want  bool
+	}{{FATAL, true}, {ERROR, true}, {WARN, true}, {INFO, true}, {DEBUG, false}} {
+		if got := l.Enabled(tt.level); got != tt.want {
+			t.Errorf("Enabled(%s) with an INFO threshold = %v; expected %v", tt.level, got, tt.want)
+		}
+	}
+	if !(FATAL > ERROR && ERROR > WARN && WARN > INFO && INFO > DEBUG) {
+		t.Errorf("Expected severities to increase from DEBUG to FATAL")
+	}
+	if err := l.SetLevel(NONE); err != nil {
+		t.Fatalf("Failed to set level: %v", err)
+	}
+	if l.Enabled(FATAL) {
+		t.Errorf("Expected NONE to filter even FATAL")
+	}
+	if err := l.Log(NONE, "not a severity"); err != ErrInvalidLogLevel {
+		t.Errorf("Expected logging at NONE to fail with ErrInvalidLogLevel, got %v", err)
+	}
+	if err := RegisterLevel("NEVER", NONE); !errors.Is(err, ErrInvalidLogLevel) {
+		t.Errorf("Expected registering NONE to fail with ErrInvalidLogLevel, got %v", err)
+	}
+	if err := RegisterLevel("WARN", INFO+1); !errors.Is(err, ErrInvalidLogLevel) {
+		t.Errorf("Expected registering a built-in name to fail with ErrInvalidLogLevel, got %v", err)
+	}
+	var zero Options
+	if zero.Level != INFO {
+		t.Errorf("Expected the zero Level to be INFO, got %s", zero.Level)
+	}
+}
+
+// TestFatalAtInfoLevel runs Fatal in a child process, as it exits, and checks it logs and exits
+// right away at the default INFO threshold.
+func TestFatalAtInfoLevel(t *testing.T) {
+	if os.Getenv("BLOG_TEST_FATAL") == "1" {
+		l, err := New(Options{Level: INFO, EnableConsole: true})
+		if err != nil {
+			os.Exit(1)
+		}
+		l.Error("error at info")
+		l.Fatal(3, 10*time.Second, "fatal at info")
+		return
+	}
+	t.Parallel()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestFatalAtInfoLevel$")
+	cmd.Env = append(os.Environ(), "BLOG_TEST_FATAL=1")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	start := time.Now()
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Fatalf("Expected exit code 3, got %v (stderr %q)", err, stderr.String())
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected Fatal to exit once logged, took %s", elapsed)
+	}
+	if out := stdout.String(); !strings.Contains(out, "ERROR") || !strings.Contains(out, "error at info") ||
+		!strings.Contains(out, "FATAL") || !strings.Contains(out, "fatal at info") {
+		t.Errorf("Expected the error and fatal messages on the console, got %q", out)
+	}
+	if strings.Contains(stderr.String(), "failed to log in time") {
+		t.Errorf("Expected the fatal message to be logged in time, got %q", stderr.String())
+	}
+}
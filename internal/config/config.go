@@ -1,10 +1,14 @@
 package config
 
 import (
+	"io"
 	"log"
+	"os"
 	"time"
 
+	"github.com/Data-Corruption/blog/v3/internal/format"
 	LogLevel "github.com/Data-Corruption/blog/v3/internal/level"
+	"github.com/Data-Corruption/blog/v3/internal/sink"
 	"github.com/Data-Corruption/blog/v3/internal/utils"
 )
 
@@ -14,6 +18,18 @@ var (
 	DefaultMaxFileSizeBytes   int               = 1024 * 1024 * 1024 // 1 GB
 	DefaultFlushInterval      time.Duration     = 15 * time.Second   // 15 seconds
 	DefaultDirectoryPath      string            = "."
+	DefaultFileEncoding       format.Encoding   = format.Text
+	DefaultConsoleEncoding    format.Encoding   = format.Text
+	DefaultTextLayout         format.Layout     = format.Layout{}
+	DefaultRotationSchedule   Schedule          = Schedule{}  // disabled
+	DefaultRetention          Retention         = Retention{} // keep everything
+	DefaultCompress           bool              = false
+	DefaultFileNaming         Naming            = Naming{} // latest.log and 2006-01-02_15-04-05.log
+	DefaultExternalRotation   bool              = false
+	DefaultOverflow           Overflow          = Overflow{}   // block
+	DefaultRecovery           Recovery          = Recovery{}   // never retry
+	DefaultSinkLevel          LogLevel.LogLevel = LogLevel.ALL // a sink only filters when told to
+	DefaultVModule            VModule           = VModule{}    // no overrides
 )
 
 // ConsoleLogger wraps *log.Logger to allow nil value semantics for disabled state
@@ -24,11 +40,27 @@ type ConsoleLogger struct {
 // Config holds the configuration settings for the Logger.
 type Config struct {
 	Level              *LogLevel.LogLevel // the minimum log level to write. Default is INFO.
+	VModule            *VModule           // per component or source file overrides of Level, e.g. "db=DEBUG,http/*=WARN". Default is none.
 	MaxBufferSizeBytes *int               // the maximum size of the write buffer before it is flushed. Default is 4 KB.
 	MaxFileSizeBytes   *int               // the maximum size of the log file before it is rotated. Default is 1 GB.
 	FlushInterval      *time.Duration     // the interval at which the write buffer is flushed. Default is 15 seconds.
+	Overflow           *Overflow          // what happens to messages logged while the message channel is full. Default blocks.
+	RotationSchedule   *Schedule          // rotates the log file at time boundaries, in addition to size based rotation. Default is disabled.
+	Retention          *Retention         // limits on the rotated log files kept, enforced at startup and after each rotation. Default keeps everything.
+	Compress           *bool              // when true, rotated log files are gzipped in the background. Default is false.
+	ExternalRotation   *bool              // when true, blog never rotates the log file itself and reopens it on SIGHUP, for tools like logrotate. Default is false.
+	FileNaming         *Naming            // the names of the active and rotated log files. Default is latest.log and 2006-01-02_15-04-05.log.
+	Recovery           *Recovery          // retries file logging with backoff after a failure made the logger fall back to the console. Default never retries.
 	DirectoryPath      *string            // the directory path where the log file is stored. Default is the current working directory ("."). To disable file logging, set this to an empty string.
+	FileEncoding       *format.Encoding   // the encoding of records written to the log file, text or JSON Lines. Default is text.
+	FileLevel          *LogLevel.LogLevel // the minimum log level written to the file, applied after Level. Default lets everything through.
+	ConsoleEncoding    *format.Encoding   // the encoding of records written to the console. Default is text.
+	ConsoleLevel       *LogLevel.LogLevel // the minimum log level written to the console, applied after Level. Default lets everything through.
+	TextLayout         *format.Layout     // the layout of text encoded records in the file and console. Default is format.DefaultTemplate.
 	ConsoleOut         *ConsoleLogger     // the logger to write to the console. Default is ConsoleLogger{l: nil}. When l is nil, console logging is disabled. This is configurable for easy testing.
+	ConsoleWriter      io.Writer          // where the console logger is created when file logging is off or fails and the console was disabled. Default is os.Stdout. When updating, nil is ignored.
+	OnError            func(error)        // called from the logger goroutine with internal errors, e.g. failed writes, on top of printing them. When updating, nil is ignored.
+	Sinks              []sink.Sink        // additional outputs next to the file and console. When updating, a non-nil slice replaces the current set and removed sinks are closed.
 }
 
 // ApplyDefaults applies the default values to the given Config if they are nil.
@@ -37,11 +69,27 @@ func (cfg *Config) ApplyDefaults() {
 		cfg = &Config{}
 	}
 	utils.SetDefaultIfNil(&cfg.Level, &DefaultLevel)
+	utils.SetDefaultIfNil(&cfg.VModule, &DefaultVModule)
 	utils.SetDefaultIfNil(&cfg.MaxBufferSizeBytes, &DefaultMaxBufferSizeBytes)
 	utils.SetDefaultIfNil(&cfg.MaxFileSizeBytes, &DefaultMaxFileSizeBytes)
 	utils.SetDefaultIfNil(&cfg.FlushInterval, &DefaultFlushInterval)
+	utils.SetDefaultIfNil(&cfg.Overflow, &DefaultOverflow)
+	utils.SetDefaultIfNil(&cfg.RotationSchedule, &DefaultRotationSchedule)
+	utils.SetDefaultIfNil(&cfg.Retention, &DefaultRetention)
+	utils.SetDefaultIfNil(&cfg.Compress, &DefaultCompress)
+	utils.SetDefaultIfNil(&cfg.ExternalRotation, &DefaultExternalRotation)
+	utils.SetDefaultIfNil(&cfg.FileNaming, &DefaultFileNaming)
+	utils.SetDefaultIfNil(&cfg.Recovery, &DefaultRecovery)
 	utils.SetDefaultIfNil(&cfg.DirectoryPath, &DefaultDirectoryPath)
+	utils.SetDefaultIfNil(&cfg.FileEncoding, &DefaultFileEncoding)
+	utils.SetDefaultIfNil(&cfg.FileLevel, &DefaultSinkLevel)
+	utils.SetDefaultIfNil(&cfg.ConsoleEncoding, &DefaultConsoleEncoding)
+	utils.SetDefaultIfNil(&cfg.ConsoleLevel, &DefaultSinkLevel)
+	utils.SetDefaultIfNil(&cfg.TextLayout, &DefaultTextLayout)
 	if cfg.ConsoleOut == nil {
 		cfg.ConsoleOut = &ConsoleLogger{}
 	}
+	if cfg.ConsoleWriter == nil {
+		cfg.ConsoleWriter = os.Stdout
+	}
 }
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Data-Corruption/blog/v3/internal/utils"
+)
+
+// Naming controls the names of log files. Empty fields use the defaults, so the zero Naming gives
+// "latest.log" for the active file and "2006-01-02_15-04-05.log" for rotated ones.
+type Naming struct {
+	Prefix     string // prepended to every file name, e.g. "api-" gives "api-latest.log". Default is none.
+	ActiveName string // name of the file being written, without prefix or extension. Default is "latest".
+	TimeLayout string // time.Format layout of rotated file names. Default is "2006-01-02_15-04-05".
+	UTC        bool   // when true rotated file names use UTC instead of local time.
+	Sequence   bool   // when true name collisions get "_1", "_2", ... suffixes instead of random ones.
+	Extension  string // file extension, including the dot. Default is ".log".
+}
+
+const (
+	DefaultActiveName = "latest"
+	DefaultTimeLayout = "2006-01-02_15-04-05"
+	DefaultExtension  = ".log"
+)
+
+// ActiveFile returns the name of the file being written, e.g. "latest.log".
+func (n Naming) ActiveFile() string {
+	return n.Prefix + utils.Ternary(n.ActiveName == "", DefaultActiveName, n.ActiveName) + n.extension()
+}
+
+// RotatedFile returns the name for a file rotated at t, e.g. "2006-01-02_15-04-05.log". Suffix
+// distinguishes files rotated at the same time, pass "" for none.
+func (n Naming) RotatedFile(t time.Time, suffix string) string {
+	if n.UTC {
+		t = t.UTC()
+	}
+	name := n.Prefix + t.Format(n.timeLayout())
+	if suffix != "" {
+		name += "_" + suffix
+	}
+	return name + n.extension()
+}
+
+// IsRotated reports whether name is a rotated file under this naming scheme, optionally
+// followed by a collision suffix and/or compressed (".gz").
+func (n Naming) IsRotated(name string) bool {
+	stem, ok := strings.CutPrefix(strings.TrimSuffix(name, ".gz"), n.Prefix)
+	if !ok {
+		return false
+	}
+	if stem, ok = strings.CutSuffix(stem, n.extension()); !ok || n.Prefix+stem+n.extension() == n.ActiveFile() {
+		return false
+	}
+	if _, err := time.Parse(n.timeLayout(), stem); err == nil {
+		return true
+	}
+	// Try every underscore as the start of a collision suffix, the layout and suffix may contain them too
+	for i := strings.IndexByte(stem, '_'); i != -1 && i < len(stem)-1; {
+		if _, err := time.Parse(n.timeLayout(), stem[:i]); err == nil {
+			return true
+		}
+		next := strings.IndexByte(stem[i+1:], '_')
+		if next == -1 {
+			break
+		}
+		i += next + 1
+	}
+	return false
+}
+
+func (n Naming) timeLayout() string {
+	return utils.Ternary(n.TimeLayout == "", DefaultTimeLayout, n.TimeLayout)
+}
+
+func (n Naming) extension() string {
+	return utils.Ternary(n.Extension == "", DefaultExtension, n.Extension)
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNamingFiles(t *testing.T) {
+	at := time.Date(2025, 2, 10, 14, 25, 0, 0, time.FixedZone("X", 2*60*60))
+	tests := []struct {
+		name            string
+		naming          Naming
+		active, rotated string
+	}{
+		{"Default", Naming{}, "latest.log", "2025-02-10_14-25-00.log"},
+		{"Prefix", Naming{Prefix: "api-"}, "api-latest.log", "api-2025-02-10_14-25-00.log"},
+		{"Custom", Naming{ActiveName: "current", TimeLayout: "20060102T1504", UTC: true, Extension: ".txt"}, "current.txt", "20250210T1225.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.naming.ActiveFile(); got != tt.active {
+				t.Errorf("ActiveFile() = %q; expected %q", got, tt.active)
+			}
+			if got := tt.naming.RotatedFile(at, ""); got != tt.rotated {
+				t.Errorf("RotatedFile() = %q; expected %q", got, tt.rotated)
+			}
+		})
+	}
+}
+
+func TestNamingIsRotated(t *testing.T) {
+	tests := []struct {
+		naming Naming
+		name   string
+		want   bool
+	}{
+		{Naming{}, "2025-02-10_14-00-00.log", true},
+		{Naming{}, "2025-02-10_14-00-00_AbCd-_12.log", true},
+		{Naming{}, "2025-02-10_14-00-00.log.gz", true},
+		{Naming{}, "2025-02-10_14-00-00_3.log.gz", true},
+		{Naming{}, "latest.log", false},
+		{Naming{}, "notes.log", false},
+		{Naming{}, "2025-02-10.log", false},
+		{Naming{}, "2025-02-10_14-00-00.txt", false},
+		{Naming{Prefix: "api-"}, "api-2025-02-10_14-00-00.log", true},
+		{Naming{Prefix: "api-"}, "2025-02-10_14-00-00.log", false},
+		{Naming{Prefix: "api-"}, "web-2025-02-10_14-00-00.log", false},
+		{Naming{Prefix: "api-"}, "api-latest.log", false},
+		{Naming{TimeLayout: "20060102", Extension: ".txt"}, "20250210_1.txt", true},
+		{Naming{TimeLayout: "20060102", Extension: ".txt"}, "2025-02-10_14-00-00.log", false},
+	}
+	for _, tt := range tests {
+		if got := tt.naming.IsRotated(tt.name); got != tt.want {
+			t.Errorf("%+v.IsRotated(%q) = %v; expected %v", tt.naming, tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package config
+
+import LogLevel "github.com/Data-Corruption/blog/v3/internal/level"
+
+// OverflowPolicy decides what happens to a message logged while the message channel is full.
+type OverflowPolicy int
+
+const (
+	Block      OverflowPolicy = iota // wait for room in the channel.
+	DropNewest                       // drop the message being logged.
+	DropOldest                       // drop the oldest queued message to make room.
+	DropBelow                        // drop messages that Overflow.Level doesn't allow, wait for the rest.
+)
+
+// Overflow configures the handling of a full message channel. The zero Overflow blocks, as
+// unbuffered logging always did. FATAL messages are never dropped.
+type Overflow struct {
+	Policy OverflowPolicy
+	Level  LogLevel.LogLevel // the threshold for DropBelow, e.g. WARN keeps ERROR and WARN messages.
+}
+
+// Keeps reports whether a message of the given level must wait for room rather than be dropped.
+func (o Overflow) Keeps(lvl LogLevel.LogLevel) bool {
+	switch o.Policy {
+	case DropNewest, DropOldest:
+		return lvl >= LogLevel.FATAL
+	case DropBelow:
+		return lvl >= LogLevel.FATAL || o.Level.Allows(lvl)
+	default:
+		return true
+	}
+}
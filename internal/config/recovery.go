@@ -0,0 +1,31 @@
+package config
+
+import (
+	"math"
+	"time"
+)
+
+// Recovery retries file logging after a write or rotate failure made the logger fall back to the
+// console. The first retry comes InitialDelay after the failure and each failed retry doubles the
+// delay, up to MaxDelay. The zero Recovery never retries.
+type Recovery struct {
+	InitialDelay time.Duration // the delay before the first retry.
+	MaxDelay     time.Duration // the longest delay between retries. Zero means no limit.
+}
+
+// Enabled reports whether file logging is retried at all.
+func (r Recovery) Enabled() bool {
+	return r.InitialDelay > 0
+}
+
+// Delay returns the delay before the given retry, counting from 0.
+func (r Recovery) Delay(attempt int) time.Duration {
+	d := r.InitialDelay
+	for i := 0; i < attempt && (r.MaxDelay <= 0 || d < r.MaxDelay) && d < math.MaxInt64/2; i++ {
+		d *= 2
+	}
+	if r.MaxDelay > 0 && d > r.MaxDelay {
+		return r.MaxDelay
+	}
+	return d
+}
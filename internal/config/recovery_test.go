@@ -0,0 +1,19 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecoveryDelay(t *testing.T) {
+	r := Recovery{InitialDelay: time.Second, MaxDelay: 10 * time.Second}
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for attempt, want := range expected {
+		if got := r.Delay(attempt); got != want {
+			t.Errorf("Delay(%d) = %s; expected %s", attempt, got, want)
+		}
+	}
+	if got := (Recovery{InitialDelay: time.Second}).Delay(40); got <= 0 {
+		t.Errorf("expected no cap to keep growing without overflowing, got %s", got)
+	}
+}
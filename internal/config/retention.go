@@ -0,0 +1,16 @@
+package config
+
+import "time"
+
+// Retention limits how many rotated log files are kept. Zero fields are unlimited, so the zero
+// Retention keeps everything. When a limit is exceeded the oldest rotated files are deleted first.
+type Retention struct {
+	MaxFiles          int           // the maximum number of rotated files to keep.
+	MaxAge            time.Duration // rotated files last written longer ago than this are deleted.
+	MaxTotalSizeBytes int           // the maximum combined size of latest.log and the rotated files.
+}
+
+// Enabled reports whether any limit is set.
+func (r Retention) Enabled() bool {
+	return r.MaxFiles > 0 || r.MaxAge > 0 || r.MaxTotalSizeBytes > 0
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"time"
+
+	"github.com/Data-Corruption/blog/v3/internal/utils"
+)
+
+const day = 24 * time.Hour
+
+// Schedule describes time based log rotation. Periods start at Offset past midnight and repeat every
+// Interval. Intervals of whole days are counted in calendar days, so daily rotation stays at the same
+// wall clock time across DST changes. Longer intervals that aren't whole days, e.g. 36 hours, are
+// counted from Offset past midnight on 1970-01-01 instead. The zero Schedule disables time based
+// rotation.
+//
+// Examples:
+//   - Hourly: Schedule{Interval: time.Hour}
+//   - Daily at 03:00 UTC: Schedule{Interval: 24 * time.Hour, Offset: 3 * time.Hour, UTC: true}
+//   - Every 15 minutes: Schedule{Interval: 15 * time.Minute}
+type Schedule struct {
+	Interval time.Duration // length of each period. 0 disables time based rotation.
+	Offset   time.Duration // where periods start relative to midnight, less than 24 hours. e.g. 3h for 03:00.
+	UTC      bool          // when true periods follow UTC, otherwise local time.
+}
+
+// Enabled reports whether the schedule rotates at all.
+func (s Schedule) Enabled() bool { return s.Interval > 0 }
+
+// Start returns the start of the period containing t.
+func (s Schedule) Start(t time.Time) time.Time {
+	if s.Interval > day && s.Interval%day != 0 {
+		epoch := s.anchorOn(1970, time.January, 1, s.location())
+		return epoch.Add(t.Sub(epoch) / s.Interval * s.Interval)
+	}
+	anchor := s.dayAnchor(t)
+	if s.Interval%day == 0 {
+		// Multi day periods are aligned to a day count since the epoch, so they line up across restarts
+		days := int64(s.Interval / day)
+		y, m, d := anchor.Date()
+		epochDays := time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / int64(day/time.Second)
+		return s.anchorOn(y, m, d-int(epochDays%days), anchor.Location())
+	}
+	return anchor.Add(t.Sub(anchor) / s.Interval * s.Interval)
+}
+
+// Next returns the start of the period after the one containing t.
+func (s Schedule) Next(t time.Time) time.Time {
+	start := s.Start(t)
+	if s.Interval > day && s.Interval%day != 0 {
+		return start.Add(s.Interval)
+	}
+	if s.Interval%day == 0 {
+		y, m, d := start.Date()
+		return s.anchorOn(y, m, d+int(s.Interval/day), start.Location())
+	}
+	// Sub day periods restart at each day's anchor, so the last period of a day may be shorter
+	y, m, d := s.dayAnchor(start).Date()
+	next := start.Add(s.Interval)
+	if tomorrow := s.anchorOn(y, m, d+1, start.Location()); next.After(tomorrow) {
+		next = tomorrow
+	}
+	return next
+}
+
+// dayAnchor returns the most recent midnight + offset at or before t, in the schedule's time zone.
+func (s Schedule) dayAnchor(t time.Time) time.Time {
+	t = t.In(s.location())
+	y, m, d := t.Date()
+	anchor := s.anchorOn(y, m, d, t.Location())
+	if anchor.After(t) {
+		anchor = s.anchorOn(y, m, d-1, t.Location())
+	}
+	return anchor
+}
+
+// location returns the schedule's time zone.
+func (s Schedule) location() *time.Location {
+	return utils.Ternary(s.UTC, time.UTC, time.Local)
+}
+
+// anchorOn returns the wall clock time Offset past midnight on the given date, which stays the same
+// across DST changes. Out of range days are normalized.
+func (s Schedule) anchorOn(y int, m time.Month, d int, loc *time.Location) time.Time {
+	o := s.Offset
+	return time.Date(y, m, d, int(o/time.Hour), int(o%time.Hour/time.Minute), int(o%time.Minute/time.Second), int(o%time.Second), loc)
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleStartAndNext(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatalf("bad time %q: %v", s, err)
+		}
+		return v
+	}
+	tests := []struct {
+		name        string
+		schedule    Schedule
+		t           string
+		start, next string
+	}{
+		{"Hourly", Schedule{Interval: time.Hour, UTC: true}, "2025-02-10T14:25:00Z", "2025-02-10T14:00:00Z", "2025-02-10T15:00:00Z"},
+		{"Hourly boundary", Schedule{Interval: time.Hour, UTC: true}, "2025-02-10T15:00:00Z", "2025-02-10T15:00:00Z", "2025-02-10T16:00:00Z"},
+		{"Daily at 03:00", Schedule{Interval: 24 * time.Hour, Offset: 3 * time.Hour, UTC: true}, "2025-02-10T02:59:00Z", "2025-02-09T03:00:00Z", "2025-02-10T03:00:00Z"},
+		{"Daily after 03:00", Schedule{Interval: 24 * time.Hour, Offset: 3 * time.Hour, UTC: true}, "2025-02-10T03:00:01Z", "2025-02-10T03:00:00Z", "2025-02-11T03:00:00Z"},
+		{"15 minutes", Schedule{Interval: 15 * time.Minute, UTC: true}, "2025-02-10T14:31:00Z", "2025-02-10T14:30:00Z", "2025-02-10T14:45:00Z"},
+		{"7 hours end of day", Schedule{Interval: 7 * time.Hour, UTC: true}, "2025-02-10T22:00:00Z", "2025-02-10T21:00:00Z", "2025-02-11T00:00:00Z"},
+		{"Two days", Schedule{Interval: 48 * time.Hour, UTC: true}, "2025-02-11T12:00:00Z", "2025-02-11T00:00:00Z", "2025-02-13T00:00:00Z"},
+		{"36 hours", Schedule{Interval: 36 * time.Hour, UTC: true}, "2025-02-10T14:25:00Z", "2025-02-09T12:00:00Z", "2025-02-11T00:00:00Z"},
+		{"36 hours next period", Schedule{Interval: 36 * time.Hour, UTC: true}, "2025-02-11T00:00:00Z", "2025-02-11T00:00:00Z", "2025-02-12T12:00:00Z"},
+		{"Two days second day", Schedule{Interval: 48 * time.Hour, UTC: true}, "2025-02-12T12:00:00Z", "2025-02-11T00:00:00Z", "2025-02-13T00:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.schedule.Start(at(tt.t)); !got.Equal(at(tt.start)) {
+				t.Errorf("Start(%s) = %s; expected %s", tt.t, got, tt.start)
+			}
+			if got := tt.schedule.Next(at(tt.t)); !got.Equal(at(tt.next)) {
+				t.Errorf("Next(%s) = %s; expected %s", tt.t, got, tt.next)
+			}
+		})
+	}
+}
+
+// Test that daily rotation in local time keeps its wall clock time across DST changes.
+func TestScheduleDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	orig := time.Local
+	time.Local = loc
+	t.Cleanup(func() { time.Local = orig })
+
+	at := func(s string) time.Time {
+		v, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatalf("bad time %q: %v", s, err)
+		}
+		return v
+	}
+	daily := Schedule{Interval: 24 * time.Hour, Offset: 3 * time.Hour}
+	tests := []struct {
+		t, start, next string
+	}{
+		{"2026-03-08T01:00:00-05:00", "2026-03-07T03:00:00-05:00", "2026-03-08T03:00:00-04:00"}, // spring forward
+		{"2026-03-08T12:00:00-04:00", "2026-03-08T03:00:00-04:00", "2026-03-09T03:00:00-04:00"},
+		{"2026-11-01T01:30:00-04:00", "2026-10-31T03:00:00-04:00", "2026-11-01T03:00:00-05:00"}, // fall back
+		{"2026-11-01T12:00:00-05:00", "2026-11-01T03:00:00-05:00", "2026-11-02T03:00:00-05:00"},
+	}
+	for _, tt := range tests {
+		if got := daily.Start(at(tt.t)); !got.Equal(at(tt.start)) {
+			t.Errorf("Start(%s) = %s; expected %s", tt.t, got, tt.start)
+		}
+		if got := daily.Next(at(tt.t)); !got.Equal(at(tt.next)) {
+			t.Errorf("Next(%s) = %s; expected %s", tt.t, got, tt.next)
+		}
+	}
+}
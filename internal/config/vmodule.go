@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	LogLevel "github.com/Data-Corruption/blog/v3/internal/level"
+)
+
+// VModule overrides the level for some components or source files, in the style of glog's -vmodule.
+// A rule matching the logger name decides a message's threshold, or else the first rule matching its
+// caller file. Messages no rule matches use Config.Level.
+type VModule []VModuleRule
+
+// VModuleRule sets the threshold for messages whose logger name or caller file matches Pattern.
+//
+// Pattern is a path.Match glob. A pattern containing a "/" or ending in ".go" matches the caller file
+// by as many trailing path elements as the pattern has, e.g. "server.go" matches any server.go and
+// "http/*" any file in a directory named http. Any other pattern matches a logger name as a whole,
+// e.g. "db" or "db.*", so it never needs the caller to be looked up.
+type VModuleRule struct {
+	Pattern string
+	Level   LogLevel.LogLevel
+}
+
+// IsFile reports whether the rule matches caller files rather than logger names.
+func (r VModuleRule) IsFile() bool {
+	return strings.Contains(r.Pattern, "/") || strings.HasSuffix(r.Pattern, ".go")
+}
+
+// ParseVModule parses comma separated pattern=LEVEL rules, e.g. "db=DEBUG,http/*=WARN". Levels are
+// read with LogLevel.FromString. An empty spec gives no rules.
+func ParseVModule(spec string) (VModule, error) {
+	var v VModule
+	for _, part := range strings.Split(spec, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		pattern, levelStr, ok := strings.Cut(part, "=")
+		pattern = strings.TrimSpace(pattern)
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("vmodule rule %q is not pattern=LEVEL", part)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("vmodule rule %q: %w", part, err)
+		}
+		var lvl LogLevel.LogLevel
+		if err := lvl.FromString(strings.TrimSpace(levelStr)); err != nil {
+			return nil, fmt.Errorf("vmodule rule %q has an invalid level", part)
+		}
+		v = append(v, VModuleRule{Pattern: pattern, Level: lvl})
+	}
+	return v, nil
+}
+
+// String returns the rules in the form ParseVModule reads.
+func (v VModule) String() string {
+	parts := make([]string, len(v))
+	for i, r := range v {
+		parts[i] = r.Pattern + "=" + r.Level.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// Level returns the threshold of the first rule matching the logger name, or else of the first
+// matching the caller file, and whether one did. Either may be empty when unknown.
+func (v VModule) Level(name, file string) (LogLevel.LogLevel, bool) {
+	if name != "" {
+		for _, r := range v {
+			if ok, _ := path.Match(r.Pattern, name); ok && !r.IsFile() {
+				return r.Level, true
+			}
+		}
+	}
+	if file != "" {
+		for _, r := range v {
+			if r.IsFile() && r.matchesFile(file) {
+				return r.Level, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func (r VModuleRule) matchesFile(file string) bool {
+	if !strings.HasSuffix(r.Pattern, ".go") {
+		file = strings.TrimSuffix(file, ".go")
+	}
+	// Compare against as many trailing elements of the file path as the pattern has.
+	tail := file
+	for i, n := len(file), strings.Count(r.Pattern, "/"); n >= 0; n-- {
+		if i = strings.LastIndexByte(file[:i], '/'); i < 0 {
+			tail = file
+			break
+		}
+		tail = file[i+1:]
+	}
+	ok, _ := path.Match(r.Pattern, tail)
+	return ok
+}
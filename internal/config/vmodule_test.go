@@ -0,0 +1,58 @@
+package config
+
+import (
+	"testing"
+
+	LogLevel "github.com/Data-Corruption/blog/v3/internal/level"
+)
+
+func TestParseVModule(t *testing.T) {
+	v, err := ParseVModule(" db=debug, http/*=WARN,,server=-8 ")
+	if err != nil {
+		t.Fatalf("ParseVModule failed: %v", err)
+	}
+	if got, want := v.String(), "db=DEBUG,http/*=WARN,server=TRACE"; got != want {
+		t.Errorf("String() = %q; expected %q", got, want)
+	}
+	if v, err := ParseVModule(""); err != nil || len(v) != 0 {
+		t.Errorf("ParseVModule(\"\") = %v, %v; expected no rules", v, err)
+	}
+	for _, invalid := range []string{"db", "=DEBUG", "db=LOUD", "[=DEBUG"} {
+		if _, err := ParseVModule(invalid); err == nil {
+			t.Errorf("ParseVModule(%q) should fail", invalid)
+		}
+	}
+}
+
+func TestVModuleLevel(t *testing.T) {
+	v := VModule{
+		{Pattern: "db", Level: LogLevel.DEBUG},
+		{Pattern: "http/*", Level: LogLevel.WARN},
+		{Pattern: "cache.*", Level: LogLevel.ERROR},
+		{Pattern: "server.go", Level: LogLevel.TRACE},
+	}
+	tests := []struct {
+		name, file string
+		want       LogLevel.LogLevel
+		ok         bool
+	}{
+		{"db", "/src/app/main.go", LogLevel.DEBUG, true},
+		{"db.pool", "/src/app/main.go", 0, false},
+		{"", "/src/app/db.go", 0, false}, // name rules never match files
+		{"", "/src/app/http/handler.go", LogLevel.WARN, true},
+		{"", "/src/app/httpx/handler.go", 0, false},
+		{"cache.lru", "", LogLevel.ERROR, true},
+		{"", "/src/app/http/server.go", LogLevel.WARN, true}, // first match wins
+		{"", "/src/app/api/server.go", LogLevel.TRACE, true},
+		{"", "server.go", LogLevel.TRACE, true},
+		{"server", "", 0, false},                                        // file rules never match names
+		{"cache.lru", "/src/app/http/handler.go", LogLevel.ERROR, true}, // the name wins
+		{"", "", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := v.Level(tt.name, tt.file)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Level(%q, %q) = %s, %v; expected %s, %v", tt.name, tt.file, got, ok, tt.want, tt.ok)
+		}
+	}
+}
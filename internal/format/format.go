@@ -0,0 +1,97 @@
+package format
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Data-Corruption/blog/v3/internal/record"
+)
+
+// Encoding selects how records are serialized.
+type Encoding int
+
+const (
+	Text Encoding = iota // human readable lines, e.g. "[2006-01-02,15-04-05,INFO]  message key=value"
+	JSON                 // JSON Lines, one object per record
+)
+
+// String returns the string representation of an Encoding.
+func (e Encoding) String() string {
+	switch e {
+	case Text:
+		return "text"
+	case JSON:
+		return "json"
+	default:
+		return "?"
+	}
+}
+
+// Append appends the newline terminated encoding of r to b and returns the extended buffer.
+func (e Encoding) Append(b []byte, r *record.Record) []byte {
+	return e.AppendLayout(b, r, nil)
+}
+
+// AppendLayout is Append with the given layout for Text, nil for the default. JSON has a fixed layout.
+func (e Encoding) AppendLayout(b []byte, r *record.Record, layout *Layout) []byte {
+	if e == JSON {
+		return AppendJSON(b, r)
+	}
+	return layout.AppendText(b, r)
+}
+
+// AppendText appends the human readable form of r in the default layout, terminated by a newline.
+// Example: "[2006-01-02,15-04-05,INFO]  [db] [main.go:42] login user=42 ip=10.0.0.1"
+func AppendText(b []byte, r *record.Record) []byte {
+	return defaultLayout.AppendText(b, r)
+}
+
+// valueString returns the string form of a field value. Like log/slog, a nil pointer error or
+// Stringer gives "<nil>" and a panic in its method gives "!PANIC: ...", rather than crashing the
+// logger goroutine.
+func valueString(v any) (s string) {
+	defer func() {
+		if r := recover(); r != nil {
+			s = fmt.Sprintf("!PANIC: %v", r)
+		}
+	}()
+	switch x := v.(type) {
+	case nil:
+		return "<nil>"
+	case string:
+		return x
+	case error:
+		if isNilPointer(x) {
+			return "<nil>"
+		}
+		return x.Error()
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		if isNilPointer(x) {
+			return "<nil>"
+		}
+		return x.String()
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+// isNilPointer reports whether v holds a nil pointer, whose methods would most likely panic.
+func isNilPointer(v any) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Pointer && rv.IsNil()
+}
+
+// needsQuoting reports whether s is empty or contains spaces, quotes, '=' or non-printable characters.
+func needsQuoting(s string) bool {
+	if s == "" {
+		return true
+	}
+	return strings.IndexFunc(s, func(r rune) bool {
+		return r == '=' || r == '"' || unicode.IsSpace(r) || !unicode.IsPrint(r)
+	}) != -1
+}
@@ -0,0 +1,192 @@
+package format
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	LogLevel "github.com/Data-Corruption/blog/v3/internal/level"
+	"github.com/Data-Corruption/blog/v3/internal/record"
+)
+
+func TestAppendText(t *testing.T) {
+	r := &record.Record{
+		Time:     time.Date(2025, 2, 10, 15, 4, 5, 0, time.Local),
+		Level:    LogLevel.INFO,
+		Location: "main.go:42",
+		Message:  "login",
+		Fields:   []record.Field{{Key: "user", Value: 42}, {Key: "note", Value: "two words"}, {Key: "empty", Value: ""}},
+	}
+	expected := `[2025-02-10,15-04-05,INFO]  [main.go:42] login user=42 note="two words" empty=""` + "\n"
+	if got := string(AppendText(nil, r)); got != expected {
+		t.Errorf("AppendText() = %q; expected %q", got, expected)
+	}
+}
+
+// nilStringer is a Stringer whose method panics on a nil receiver.
+type nilStringer struct{ name string }
+
+func (s *nilStringer) String() string { return s.name }
+
+// panicStringer is a Stringer whose method always panics.
+type panicStringer struct{}
+
+func (panicStringer) String() string { panic("boom") }
+
+// Test that nil pointer and panicking field values are written as placeholders rather than crash.
+func TestAppendTextBadValues(t *testing.T) {
+	var nilErr *json.SyntaxError
+	r := &record.Record{
+		Time:    time.Date(2025, 2, 10, 15, 4, 5, 0, time.Local),
+		Level:   LogLevel.INFO,
+		Message: "request",
+		Fields:  []record.Field{{Key: "req", Value: (*nilStringer)(nil)}, {Key: "err", Value: nilErr}, {Key: "p", Value: panicStringer{}}},
+	}
+	expected := `[2025-02-10,15-04-05,INFO]  request req=<nil> err=<nil> p="!PANIC: boom"` + "\n"
+	if got := string(AppendText(nil, r)); got != expected {
+		t.Errorf("AppendText() = %q; expected %q", got, expected)
+	}
+}
+
+func TestAppendJSON(t *testing.T) {
+	ts := time.Date(2025, 2, 10, 15, 4, 5, 123456789, time.UTC)
+	r := &record.Record{
+		Time:    ts,
+		Level:   LogLevel.WARN,
+		Message: "line one\nline \"two\"\x00\x1b\u2028\xff",
+		Fields: []record.Field{
+			{Key: "user", Value: 42},
+			{Key: "ok", Value: true},
+			{Key: "err", Value: errors.New("boom\n")},
+			{Key: "tags", Value: []string{"a", "b"}},
+			{Key: "nil", Value: nil},
+		},
+	}
+	out := AppendJSON(nil, r)
+	if strings.Count(string(out), "\n") != 1 || out[len(out)-1] != '\n' {
+		t.Fatalf("expected a single newline terminated line, got %q", out)
+	}
+
+	var decoded struct {
+		Time     string         `json:"time"`
+		Level    string         `json:"level"`
+		Location *string        `json:"location"`
+		Message  string         `json:"message"`
+		Fields   map[string]any `json:"fields"`
+	}
+	if err := json.Unmarshal(out, &decoded); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, out)
+	}
+	if decoded.Time != ts.Format(time.RFC3339Nano) {
+		t.Errorf("time = %q; expected %q", decoded.Time, ts.Format(time.RFC3339Nano))
+	}
+	if decoded.Level != "WARN" {
+		t.Errorf("level = %q; expected %q", decoded.Level, "WARN")
+	}
+	if decoded.Location != nil {
+		t.Errorf("expected location to be omitted, got %q", *decoded.Location)
+	}
+	if expected := "line one\nline \"two\"\x00\x1b\u2028\ufffd"; decoded.Message != expected {
+		t.Errorf("message = %q; expected %q", decoded.Message, expected)
+	}
+	if decoded.Fields["user"] != float64(42) || decoded.Fields["ok"] != true || decoded.Fields["err"] != "boom\n" {
+		t.Errorf("unexpected fields: %v", decoded.Fields)
+	}
+	if tags, ok := decoded.Fields["tags"].([]any); !ok || len(tags) != 2 {
+		t.Errorf("expected tags to be an array, got %v", decoded.Fields["tags"])
+	}
+	if v, ok := decoded.Fields["nil"]; !ok || v != nil {
+		t.Errorf("expected nil field to be null, got %v", v)
+	}
+}
+
+// nilMarshaler is a json.Marshaler whose method panics on a nil receiver.
+type nilMarshaler struct{ data []byte }
+
+func (m *nilMarshaler) MarshalJSON() ([]byte, error) { return m.data, nil }
+
+// Test that nil pointer and panicking field values are written as placeholders rather than crash.
+func TestAppendJSONBadValues(t *testing.T) {
+	var nilErr *json.SyntaxError
+	r := &record.Record{
+		Time:    time.Date(2025, 2, 10, 15, 4, 5, 0, time.UTC),
+		Level:   LogLevel.INFO,
+		Message: "request",
+		Fields: []record.Field{
+			{Key: "err", Value: nilErr},
+			{Key: "m", Value: (*nilMarshaler)(nil)},
+			{Key: "req", Value: (*nilStringer)(nil)},
+			{Key: "p", Value: panicStringer{}},
+		},
+	}
+	var decoded struct {
+		Fields map[string]any `json:"fields"`
+	}
+	out := AppendJSON(nil, r)
+	if err := json.Unmarshal(out, &decoded); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, out)
+	}
+	for key, expected := range map[string]any{"err": nil, "m": nil, "req": nil, "p": "!PANIC: boom"} {
+		if v, ok := decoded.Fields[key]; !ok || v != expected {
+			t.Errorf("field %s = %v; expected %v in %s", key, v, expected, out)
+		}
+	}
+}
+
+func TestLayout(t *testing.T) {
+	r := &record.Record{
+		Time:    time.Date(2025, 2, 10, 15, 4, 5, 123456789, time.FixedZone("CET", 3600)),
+		Level:   LogLevel.WARN,
+		Name:    "db",
+		Message: "slow query",
+		Fields:  []record.Field{{Key: "ms", Value: 250}},
+	}
+	tests := []struct {
+		opts     LayoutOptions
+		expected string
+	}{
+		{LayoutOptions{}, "[2025-02-10,15-04-05,WARN]  [db] slow query ms=250\n"},
+		{LayoutOptions{Template: "{time} {level} {message}", TimeLayout: time.TimeOnly, UTC: true, Precision: 3}, "14:04:05.123 WARN slow query\n"},
+		{LayoutOptions{Template: "{time} {message}", TimeLayout: time.RFC3339, UTC: true, Precision: 3}, "2025-02-10T14:04:05.123Z slow query\n"},
+		{LayoutOptions{Template: "{time} {message}", TimeLayout: "3:4:5PM", Precision: 2}, "3:4:5.12PM slow query\n"},
+		{LayoutOptions{Template: "{level}|{message}", LevelCase: LowerCase, LevelWidth: 5}, "warn |slow query\n"},
+		{LayoutOptions{Template: "{level}|{message}", LevelCase: TitleCase, LevelWidth: 3}, "War|slow query\n"},
+		{LayoutOptions{Template: "{fields} {name}{pad=16}{message}{location}"}, " ms=250 [db]    slow query\n"},
+	}
+	for _, tt := range tests {
+		l, err := NewLayout(tt.opts)
+		if err != nil {
+			t.Fatalf("NewLayout(%+v) failed: %v", tt.opts, err)
+		}
+		if got := string(l.AppendText([]byte("existing "), r)); got != "existing "+tt.expected {
+			t.Errorf("AppendText() with %+v = %q; expected %q", tt.opts, got, "existing "+tt.expected)
+		}
+	}
+
+	// Only the built-in names are cut, "INF" would be ambiguous for INFO+2.
+	short, _ := NewLayout(LayoutOptions{Template: "{level}|{message}", LevelWidth: 3})
+	between := *r
+	between.Level = LogLevel.INFO + 2
+	if got, expected := string(short.AppendText(nil, &between)), "INFO+2|slow query\n"; got != expected {
+		t.Errorf("AppendText() of INFO+2 = %q; expected %q", got, expected)
+	}
+
+	var zero Layout
+	if got, expected := string(zero.AppendText(nil, r)), string(AppendText(nil, r)); got != expected {
+		t.Errorf("zero Layout = %q; expected the default %q", got, expected)
+	}
+	for _, invalid := range []LayoutOptions{
+		{Template: "{time}"},
+		{Template: "{message} {msg}"},
+		{Template: "{message} {time"},
+		{Template: "{pad=x}{message}"},
+		{Precision: 10},
+		{TimeLayout: time.Kitchen, Precision: 3}, // no seconds to add the fraction to
+	} {
+		if _, err := NewLayout(invalid); err == nil {
+			t.Errorf("NewLayout(%+v) should fail", invalid)
+		}
+	}
+}
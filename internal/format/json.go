@@ -0,0 +1,161 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Data-Corruption/blog/v3/internal/record"
+)
+
+const hex = "0123456789abcdef"
+
+// AppendJSON appends r as a single line JSON object, terminated by a newline.
+// Example: {"time":"2006-01-02T15:04:05.999999999Z","level":"INFO","message":"login","fields":{"user":42}}
+// The logger and location keys are omitted when empty, as is the fields key when there are no fields.
+func AppendJSON(b []byte, r *record.Record) []byte {
+	b = append(b, `{"time":`...)
+	b = appendJSONString(b, r.Time.Format(time.RFC3339Nano))
+	b = append(b, `,"level":`...)
+	b = appendJSONString(b, r.Level.String())
+	if r.Name != "" {
+		b = append(b, `,"logger":`...)
+		b = appendJSONString(b, r.Name)
+	}
+	if r.Location != "" {
+		b = append(b, `,"location":`...)
+		b = appendJSONString(b, r.Location)
+	}
+	b = append(b, `,"message":`...)
+	b = appendJSONString(b, r.Message)
+	if len(r.Fields) > 0 {
+		b = append(b, `,"fields":{`...)
+		for i, f := range r.Fields {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = appendJSONString(b, f.Key)
+			b = append(b, ':')
+			b = appendJSONValue(b, f.Value)
+		}
+		b = append(b, '}')
+	}
+	return append(b, "}\n"...)
+}
+
+// appendJSONValue appends v as a JSON value. Common types are handled directly,
+// anything else goes through encoding/json and falls back to its string form. As with valueString,
+// a nil pointer error, Marshaler or Stringer gives null and a panic in a method "!PANIC: ...".
+func appendJSONValue(b []byte, v any) (out []byte) {
+	defer func() {
+		if r := recover(); r != nil { // a method of the value panicked, drop what it wrote
+			out = appendJSONString(b, fmt.Sprintf("!PANIC: %v", r))
+		}
+	}()
+	switch x := v.(type) {
+	case nil:
+		return append(b, "null"...)
+	case string:
+		return appendJSONString(b, x)
+	case bool:
+		return strconv.AppendBool(b, x)
+	case int:
+		return strconv.AppendInt(b, int64(x), 10)
+	case int32:
+		return strconv.AppendInt(b, int64(x), 10)
+	case int64:
+		return strconv.AppendInt(b, x, 10)
+	case uint:
+		return strconv.AppendUint(b, uint64(x), 10)
+	case uint32:
+		return strconv.AppendUint(b, uint64(x), 10)
+	case uint64:
+		return strconv.AppendUint(b, x, 10)
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return appendJSONString(b, strconv.FormatFloat(x, 'g', -1, 64))
+		}
+		return strconv.AppendFloat(b, x, 'g', -1, 64)
+	case time.Time:
+		return appendJSONString(b, x.Format(time.RFC3339Nano))
+	case time.Duration:
+		return appendJSONString(b, x.String())
+	case error:
+		if isNilPointer(x) {
+			return append(b, "null"...)
+		}
+		return appendJSONString(b, x.Error())
+	case json.Marshaler:
+		if isNilPointer(x) {
+			return append(b, "null"...)
+		}
+		if data, err := x.MarshalJSON(); err == nil && json.Valid(data) {
+			return append(b, data...)
+		}
+	case fmt.Stringer:
+		if isNilPointer(x) {
+			return append(b, "null"...)
+		}
+		return appendJSONString(b, x.String())
+	}
+	if data, err := json.Marshal(v); err == nil {
+		return append(b, data...)
+	}
+	return appendJSONString(b, valueString(v))
+}
+
+// appendJSONString appends s as a quoted JSON string. Quotes, backslashes and all control
+// characters are escaped and invalid UTF-8 is replaced with U+FFFD, so the output is always
+// a single valid line.
+func appendJSONString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if r == utf8.RuneError && size == 1 {
+				b = append(b, s[start:i]...)
+				b = append(b, `\ufffd`...)
+				i += size
+				start = i
+				continue
+			}
+			// U+2028 and U+2029 are valid JSON but break some JavaScript based line readers.
+			if r == '\u2028' || r == '\u2029' {
+				b = append(b, s[start:i]...)
+				b = append(b, `\u202`...)
+				b = append(b, hex[r&0xF])
+				i += size
+				start = i
+				continue
+			}
+			i += size
+			continue
+		}
+		if c >= 0x20 && c != '"' && c != '\\' && c != 0x7f {
+			i++
+			continue
+		}
+		b = append(b, s[start:i]...)
+		switch c {
+		case '"', '\\':
+			b = append(b, '\\', c)
+		case '\n':
+			b = append(b, '\\', 'n')
+		case '\r':
+			b = append(b, '\\', 'r')
+		case '\t':
+			b = append(b, '\\', 't')
+		default:
+			b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+		}
+		i++
+		start = i
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
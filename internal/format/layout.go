@@ -0,0 +1,248 @@
+package format
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	LogLevel "github.com/Data-Corruption/blog/v3/internal/level"
+	"github.com/Data-Corruption/blog/v3/internal/record"
+)
+
+// DefaultTemplate is the template of the default text layout.
+// Example: "[2006-01-02,15-04-05,INFO]  [db] [main.go:42] login user=42 ip=10.0.0.1"
+const DefaultTemplate = "[{time},{level}] {pad=28}{name}{location}{message}{fields}"
+
+// DefaultTimeLayout is the time.Format layout of {time} in the default text layout.
+const DefaultTimeLayout = "2006-01-02,15-04-05"
+
+// LevelCase selects how level names are written in text output.
+type LevelCase int
+
+const (
+	UpperCase LevelCase = iota // e.g. "WARN"
+	LowerCase                  // e.g. "warn"
+	TitleCase                  // e.g. "Warn"
+)
+
+// LayoutOptions describes the layout of text output. The zero LayoutOptions gives the default layout.
+// LevelWidth only cuts the built-in level names, others like "INFO+2" or registered ones stay whole
+// since a cut would make them ambiguous.
+//
+// Template places the parts of a line with these placeholders, anything else is copied as is:
+//   - {time}     the record's time, see TimeLayout, UTC and Precision.
+//   - {level}    the level name, see LevelCase and LevelWidth.
+//   - {name}     the logger name as "[db] ", nothing for the root logger.
+//   - {location} the caller as "[main.go:42] ", nothing when the record has no location.
+//   - {message}  the message, required.
+//   - {fields}   the fields as " key=value" each, values quoted when needed.
+//   - {pad=N}    pads the line so far with spaces to N characters.
+type LayoutOptions struct {
+	Template   string    // the order of the parts of a line. Default is DefaultTemplate.
+	TimeLayout string    // time.Format layout of {time}. Default is DefaultTimeLayout.
+	UTC        bool      // when true {time} is in UTC instead of local time.
+	Precision  int       // digits of sub-second precision added after the seconds of {time}, up to 9. Default is none.
+	LevelCase  LevelCase // the casing of ASCII letters in level names. Default is UpperCase.
+	LevelWidth int       // when > 0, level names are padded to this many characters and built-in ones cut to it, e.g. 3 gives "INF", "WAR", "ERR".
+}
+
+// Layout is the compiled form of LayoutOptions, created with NewLayout. The zero Layout is the
+// default layout.
+type Layout struct {
+	parts      []part
+	timeLayout string
+	utc        bool
+	levelCase  LevelCase
+	levelWidth int
+}
+
+type partKind int
+
+const (
+	literalPart partKind = iota
+	timePart
+	levelPart
+	namePart
+	locationPart
+	messagePart
+	fieldsPart
+	padPart
+)
+
+// part is a placeholder, or literal text.
+type part struct {
+	kind  partKind
+	text  string // for literalPart
+	width int    // for padPart
+}
+
+var placeholders = map[string]partKind{
+	"time":     timePart,
+	"level":    levelPart,
+	"name":     namePart,
+	"location": locationPart,
+	"message":  messagePart,
+	"fields":   fieldsPart,
+}
+
+// defaultLayout is used by the zero Layout.
+var defaultLayout, _ = NewLayout(LayoutOptions{})
+
+// NewLayout compiles the given options, returning an error for an invalid template or precision.
+func NewLayout(o LayoutOptions) (Layout, error) {
+	if o.Template == "" {
+		o.Template = DefaultTemplate
+	}
+	if o.TimeLayout == "" {
+		o.TimeLayout = DefaultTimeLayout
+	}
+	if o.Precision < 0 || o.Precision > 9 {
+		return Layout{}, fmt.Errorf("precision %d is not between 0 and 9", o.Precision)
+	}
+	if o.Precision > 0 {
+		end := secondsEnd(o.TimeLayout)
+		if end == -1 {
+			return Layout{}, fmt.Errorf("precision needs seconds in time layout %q", o.TimeLayout)
+		}
+		o.TimeLayout = o.TimeLayout[:end] + "." + strings.Repeat("0", o.Precision) + o.TimeLayout[end:]
+	}
+	l := Layout{timeLayout: o.TimeLayout, utc: o.UTC, levelCase: o.LevelCase, levelWidth: o.LevelWidth}
+	hasMessage := false
+	for rest := o.Template; rest != ""; {
+		start := strings.IndexByte(rest, '{')
+		if start == -1 {
+			l.parts = append(l.parts, part{kind: literalPart, text: rest})
+			break
+		}
+		if start > 0 {
+			l.parts = append(l.parts, part{kind: literalPart, text: rest[:start]})
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end == -1 {
+			return Layout{}, fmt.Errorf("unclosed placeholder in template %q", o.Template)
+		}
+		name := rest[start+1 : start+end]
+		rest = rest[start+end+1:]
+		if width, ok := strings.CutPrefix(name, "pad="); ok {
+			n, err := strconv.Atoi(width)
+			if err != nil || n < 0 {
+				return Layout{}, fmt.Errorf("invalid width in {%s}", name)
+			}
+			l.parts = append(l.parts, part{kind: padPart, width: n})
+			continue
+		}
+		kind, ok := placeholders[name]
+		if !ok {
+			return Layout{}, fmt.Errorf("unknown placeholder {%s}", name)
+		}
+		hasMessage = hasMessage || kind == messagePart
+		l.parts = append(l.parts, part{kind: kind})
+	}
+	if !hasMessage {
+		return Layout{}, fmt.Errorf("template %q has no {message}", o.Template)
+	}
+	return l, nil
+}
+
+// secondsEnd returns the index just past the seconds element ("05" or "5") of a time.Format layout,
+// where the fraction goes, or -1 when it has none.
+func secondsEnd(layout string) int {
+	for i := 0; i < len(layout); i++ {
+		switch {
+		case strings.HasPrefix(layout[i:], "15"): // the hour
+			i++
+		case strings.HasPrefix(layout[i:], "05"):
+			return i + 2
+		case layout[i] == '5':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// AppendText appends r in this layout, terminated by a newline.
+func (l *Layout) AppendText(b []byte, r *record.Record) []byte {
+	if l == nil || l.parts == nil {
+		l = &defaultLayout
+	}
+	start := len(b)
+	for _, p := range l.parts {
+		switch p.kind {
+		case literalPart:
+			b = append(b, p.text...)
+		case timePart:
+			b = l.appendTime(b, r.Time)
+		case levelPart:
+			b = l.appendLevel(b, r.Level)
+		case namePart:
+			b = appendBracketed(b, r.Name)
+		case locationPart:
+			b = appendBracketed(b, r.Location)
+		case messagePart:
+			b = append(b, r.Message...)
+		case fieldsPart:
+			b = appendFields(b, r.Fields)
+		case padPart:
+			for len(b)-start < p.width {
+				b = append(b, ' ')
+			}
+		}
+	}
+	return append(b, '\n')
+}
+
+func (l *Layout) appendTime(b []byte, t time.Time) []byte {
+	if l.utc {
+		t = t.UTC()
+	}
+	return t.AppendFormat(b, l.timeLayout)
+}
+
+func (l *Layout) appendLevel(b []byte, lvl LogLevel.LogLevel) []byte {
+	name := lvl.String()
+	if l.levelWidth > 0 && len(name) > l.levelWidth && lvl.IsBuiltin() {
+		name = name[:l.levelWidth]
+	}
+	start := len(b)
+	b = append(b, name...)
+	for i := start; i < len(b); i++ {
+		upper := l.levelCase == UpperCase || (l.levelCase == TitleCase && i == start)
+		switch c := b[i]; {
+		case upper && 'a' <= c && c <= 'z':
+			b[i] = c - 'a' + 'A'
+		case !upper && 'A' <= c && c <= 'Z':
+			b[i] = c - 'A' + 'a'
+		}
+	}
+	for len(b)-start < l.levelWidth {
+		b = append(b, ' ')
+	}
+	return b
+}
+
+// appendBracketed appends "[s] ", or nothing when s is empty.
+func appendBracketed(b []byte, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = append(b, '[')
+	b = append(b, s...)
+	return append(b, "] "...)
+}
+
+// appendFields appends " key=value" for each field, quoting values when needed.
+func appendFields(b []byte, fields []record.Field) []byte {
+	for _, f := range fields {
+		b = append(b, ' ')
+		b = append(b, f.Key...)
+		b = append(b, '=')
+		s := valueString(f.Value)
+		if needsQuoting(s) {
+			b = strconv.AppendQuote(b, s)
+		} else {
+			b = append(b, s...)
+		}
+	}
+	return b
+}
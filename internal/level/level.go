@@ -2,22 +2,89 @@ package LogLevel
 
 import (
 	"fmt"
+	"math"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
+// LogLevel is both the severity of a message and the threshold a logger or output filters with.
+// Higher is more severe, a threshold lets through messages at least as severe as itself. The values
+// match log/slog's, with gaps left for levels in between, see Register.
 type LogLevel int
 
+// Severities of messages, also usable as thresholds.
 const (
-	NONE LogLevel = iota
-	ERROR
-	WARN
-	INFO
-	DEBUG
-	FATAL
+	TRACE LogLevel = -8
+	DEBUG LogLevel = -4
+	INFO  LogLevel = 0 // the zero value
+	WARN  LogLevel = 4
+	ERROR LogLevel = 8
+	FATAL LogLevel = 12
 )
 
-// String returns the string representation of a blog.Level.
-func (l LogLevel) String() string {
+// Thresholds only, no message has these severities.
+const (
+	NONE LogLevel = math.MaxInt // lets nothing through, not even FATAL.
+	ALL  LogLevel = math.MinInt // lets everything through.
+)
+
+// builtin lists the named severities from least to most severe.
+var builtin = []LogLevel{TRACE, DEBUG, INFO, WARN, ERROR, FATAL}
+
+// registry holds the levels added with Register. It is replaced, never modified, so String can
+// read it without locking.
+type registry struct {
+	names  map[LogLevel]string
+	levels map[string]LogLevel
+}
+
+var (
+	registered   atomic.Pointer[registry]
+	registerLock sync.Mutex
+)
+
+// Register adds a named level with the given severity, e.g. Register("NOTICE", INFO+2) for a level
+// between INFO and WARN. Names are case-insensitive and stored upper case. Neither the name nor the
+// severity may already be in use, except that registering the same level again does nothing. Levels
+// are process wide, register them once at startup.
+func Register(name string, severity LogLevel) error {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if name == "" || strings.ContainsAny(name, "+- \t\n\"") {
+		return fmt.Errorf("level name %q must be a single word without signs or quotes", name)
+	}
+	if _, err := strconv.Atoi(name); err == nil {
+		return fmt.Errorf("level name %q must not be a number", name)
+	}
+	registerLock.Lock()
+	defer registerLock.Unlock()
+	if r := registered.Load(); r != nil && r.names[severity] == name {
+		return nil
+	}
+	var taken LogLevel
+	if taken.FromString(name) == nil {
+		return fmt.Errorf("level name %q is already in use", name)
+	}
+	if severity.IsThreshold() {
+		return fmt.Errorf("%s is a threshold, not a severity", severity)
+	}
+	if severity.name() != "" {
+		return fmt.Errorf("severity %d is already in use", int(severity))
+	}
+	next := &registry{names: map[LogLevel]string{severity: name}, levels: map[string]LogLevel{name: severity}}
+	if r := registered.Load(); r != nil {
+		for lvl, n := range r.names {
+			next.names[lvl], next.levels[n] = n, lvl
+		}
+	}
+	registered.Store(next)
+	return nil
+}
+
+// name returns the name of a built-in or registered level, or "" for other severities.
+func (l LogLevel) name() string {
 	// switch for perf
 	switch l {
 	case NONE:
@@ -30,31 +97,97 @@ func (l LogLevel) String() string {
 		return "INFO"
 	case DEBUG:
 		return "DEBUG"
+	case TRACE:
+		return "TRACE"
 	case FATAL:
 		return "FATAL"
-	default:
-		return "?"
+	case ALL:
+		return "ALL"
+	}
+	if r := registered.Load(); r != nil {
+		return r.names[l]
 	}
+	return ""
+}
+
+// String returns the name of a built-in or registered level. Other severities are shown relative to
+// the closest built-in level below them, e.g. "INFO+2", as log/slog does.
+func (l LogLevel) String() string {
+	if name := l.name(); name != "" {
+		return name
+	}
+	base := builtin[0]
+	for _, b := range builtin {
+		if b <= l {
+			base = b
+		}
+	}
+	return fmt.Sprintf("%s%+d", base.name(), int(l-base))
+}
+
+// IsBuiltin reports whether l is one of the built-in severities, TRACE to FATAL.
+func (l LogLevel) IsBuiltin() bool {
+	return slices.Contains(builtin, l)
+}
+
+// IsThreshold reports whether l is NONE or ALL, which only filter and are never the severity of a message.
+func (l LogLevel) IsThreshold() bool {
+	return l == NONE || l == ALL
+}
+
+// Allows reports whether a message of severity lvl passes when l is used as the threshold, that is
+// whether it is at least as severe. NONE allows nothing.
+func (l LogLevel) Allows(lvl LogLevel) bool {
+	return l != NONE && lvl >= l
 }
 
 // FromString sets a blog.Level from a string, returning ErrInvalidLogLevel if the string is invalid.
-// Case-insensitive. Example: "ERROR" -> ERROR, "error" -> ERROR, "Error" -> ERROR, etc.
+// Case-insensitive, and accepts registered levels, offsets as written by String and numeric severities.
+// Example: "ERROR" -> ERROR, "error" -> ERROR, "Error" -> ERROR, "info+2" -> INFO+2, "4" -> WARN, etc.
 func (l *LogLevel) FromString(levelStr string) error {
-	switch strings.ToUpper(levelStr) {
+	if n, err := strconv.Atoi(levelStr); err == nil {
+		*l = LogLevel(n)
+		return nil
+	}
+	name, offset := strings.ToUpper(levelStr), 0
+	if i := strings.IndexAny(name, "+-"); i > 0 {
+		n, err := strconv.Atoi(name[i:])
+		if err != nil {
+			return fmt.Errorf("blog: invalid log level")
+		}
+		name, offset = name[:i], n
+	}
+	var lvl LogLevel
+	switch name {
 	case "NONE":
-		*l = NONE
+		lvl = NONE
 	case "ERROR":
-		*l = ERROR
+		lvl = ERROR
 	case "WARN":
-		*l = WARN
+		lvl = WARN
 	case "INFO":
-		*l = INFO
+		lvl = INFO
 	case "DEBUG":
-		*l = DEBUG
+		lvl = DEBUG
+	case "TRACE":
+		lvl = TRACE
 	case "FATAL":
-		*l = FATAL
+		lvl = FATAL
+	case "ALL":
+		lvl = ALL
 	default:
+		r := registered.Load()
+		if r == nil {
+			return fmt.Errorf("blog: invalid log level")
+		}
+		var ok bool
+		if lvl, ok = r.levels[name]; !ok {
+			return fmt.Errorf("blog: invalid log level")
+		}
+	}
+	if offset != 0 && (lvl == NONE || lvl == ALL) {
 		return fmt.Errorf("blog: invalid log level")
 	}
+	*l = lvl + LogLevel(offset)
 	return nil
 }
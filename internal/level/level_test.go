@@ -0,0 +1,88 @@
+package LogLevel
+
+import "testing"
+
+func TestLevelStringRoundTrip(t *testing.T) {
+	tests := []struct {
+		level LogLevel
+		str   string
+	}{
+		{TRACE, "TRACE"},
+		{DEBUG, "DEBUG"},
+		{INFO, "INFO"},
+		{WARN, "WARN"},
+		{ERROR, "ERROR"},
+		{FATAL, "FATAL"},
+		{NONE, "NONE"},
+		{INFO + 2, "INFO+2"},
+		{TRACE - 1, "TRACE-1"},
+		{FATAL + 4, "FATAL+4"},
+	}
+	for _, tt := range tests {
+		if got := tt.level.String(); got != tt.str {
+			t.Errorf("%d.String() = %q; expected %q", int(tt.level), got, tt.str)
+		}
+		var parsed LogLevel
+		if err := parsed.FromString(tt.str); err != nil || parsed != tt.level {
+			t.Errorf("FromString(%q) = %d, %v; expected %d", tt.str, int(parsed), err, int(tt.level))
+		}
+	}
+
+	var l LogLevel
+	for str, want := range map[string]LogLevel{"4": WARN, "-8": TRACE, "+2": INFO + 2} {
+		if err := l.FromString(str); err != nil || l != want {
+			t.Errorf("FromString(%q) = %d, %v; expected %d", str, int(l), err, int(want))
+		}
+	}
+	for _, invalid := range []string{"", "LOUD", "INFO+", "INFO+x", "NONE+1", "4.5"} {
+		if err := l.FromString(invalid); err == nil {
+			t.Errorf("FromString(%q) should fail", invalid)
+		}
+	}
+}
+
+// resetRegistered removes the levels registered by a test once it ends, so tests can run in any
+// order and more than once.
+func resetRegistered(t *testing.T) {
+	t.Cleanup(func() { registered.Store(nil) })
+}
+
+func TestLevelRegister(t *testing.T) {
+	resetRegistered(t)
+	if err := Register("notice", INFO+2); err != nil {
+		t.Fatalf("failed to register NOTICE: %v", err)
+	}
+	if err := Register("NOTICE", INFO+2); err != nil {
+		t.Errorf("registering the same level again should do nothing, got %v", err)
+	}
+	if got := (INFO + 2).String(); got != "NOTICE" {
+		t.Errorf("String() = %q; expected %q", got, "NOTICE")
+	}
+	var l LogLevel
+	if err := l.FromString("Notice+1"); err != nil || l != INFO+3 {
+		t.Errorf("FromString(%q) = %d, %v; expected %d", "Notice+1", int(l), err, int(INFO+3))
+	}
+	if !WARN.Allows(FATAL) || WARN.Allows(INFO+2) || !(INFO + 1).Allows(INFO+2) {
+		t.Errorf("registered levels should filter by severity")
+	}
+
+	conflicts := []struct {
+		name     string
+		severity LogLevel
+	}{
+		{"NOTICE", INFO + 1}, // name taken
+		{"debug", INFO + 1},  // built-in name
+		{"AUDIT", INFO + 2},  // severity taken
+		{"AUDIT", WARN},      // built-in severity
+		{"AUDIT", NONE},
+		{"", INFO + 1},
+		{"AU DIT", INFO + 1},
+		{"AUDIT+1", INFO + 1},
+		{"42", INFO + 1},
+	}
+	for _, c := range conflicts {
+		if err := Register(c.name, c.severity); err == nil {
+			t.Errorf("Register(%q, %d) should fail", c.name, int(c.severity))
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// compressFile gzips path into path+".gz" and removes path. It writes to a temporary file first and
+// renames it into place, so a crash or error never leaves a partial .gz file or loses the original.
+func compressFile(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	// Write the compressed data to a temporary file
+	tmpPath := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			dst.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+	zw := gzip.NewWriter(dst)
+	zw.Name = info.Name()
+	zw.ModTime = info.ModTime()
+	if _, err = io.Copy(zw, src); err != nil {
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		return err
+	}
+	if err = dst.Sync(); err != nil {
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	// Keep the original modification time, retention uses it to find the oldest files
+	if err = os.Chtimes(tmpPath, info.ModTime(), info.ModTime()); err != nil {
+		return err
+	}
+	// Move it into place, then remove the original
+	if err = os.Rename(tmpPath, path+".gz"); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// compressInBackground compresses a rotated file without blocking the logger goroutine.
+// Failures are handed back to the logger goroutine to report, and leave the original file in place.
+func (s *fileSink) compressInBackground(path string) {
+	if _, busy := s.inFlight.LoadOrStore(path, struct{}{}); busy {
+		return
+	}
+	errChan := s.l.compressErrChan
+	s.compressing.Add(1)
+	go func() {
+		defer s.compressing.Done()
+		defer s.inFlight.Delete(path)
+		if err := compressFile(path); err != nil {
+			err = fmt.Errorf("%w %s: %w", ErrCompress, path, err)
+			select {
+			case errChan <- err:
+			default: // the logger is busy or gone, don't block
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+	}()
+}
+
+// isCompressing reports whether path is being compressed in the background.
+func (s *fileSink) isCompressing(path string) bool {
+	_, ok := s.inFlight.Load(path)
+	return ok
+}
+
+// waitCompression waits for the background compressions to finish, until the deadline if not zero.
+// Any still running after it are left to finish on their own, or redone by recoverCompression.
+func (s *fileSink) waitCompression(deadline time.Time) {
+	done := make(chan struct{})
+	go func() {
+		s.compressing.Wait()
+		close(done)
+	}()
+	if deadline.IsZero() {
+		<-done
+		return
+	}
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+	}
+}
+
+// recoverCompression cleans up after compressions a crash or an early exit interrupted. Leftover
+// temporary files are removed, and when Compress is on, rotated files that are still uncompressed
+// are compressed again.
+func (s *fileSink) recoverCompression() {
+	dir := *s.l.config.DirectoryPath
+	if dir == "" {
+		return
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		s.l.reportError(fmt.Errorf("%w: failed to list the log directory: %w", ErrCompress, err))
+		return
+	}
+	var pending []string
+	for _, e := range entries {
+		name := e.Name()
+		if !e.Type().IsRegular() {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if orig, ok := strings.CutSuffix(name, ".gz.tmp"); ok {
+			if s.l.config.FileNaming.IsRotated(orig) && !s.isCompressing(filepath.Join(dir, orig)) {
+				if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+					s.l.reportError(fmt.Errorf("%w: %w", ErrCompress, err))
+				}
+			}
+			continue
+		}
+		if *s.l.config.Compress && !strings.HasSuffix(name, ".gz") && s.l.config.FileNaming.IsRotated(name) {
+			pending = append(pending, path)
+		}
+	}
+	// Only start once every leftover is gone, a new compression writes to the same temporary path
+	for _, path := range pending {
+		s.compressInBackground(path)
+	}
+}
@@ -0,0 +1,26 @@
+package logger
+
+import (
+	"github.com/Data-Corruption/blog/v3/internal/record"
+)
+
+// consoleSink is the built-in sink that prints records to the config's ConsoleOut logger, using the
+// console level, encoding and text layout. It does nothing while ConsoleOut.L is nil.
+type consoleSink struct {
+	l *Logger
+
+	// Scratch space for encoding a single record, reused to avoid allocations.
+	encodeBuf []byte
+}
+
+func (s *consoleSink) Write(r *record.Record) error {
+	if s.l.config.ConsoleOut.L == nil || !s.l.config.ConsoleLevel.Allows(r.Level) {
+		return nil
+	}
+	s.encodeBuf = s.l.config.ConsoleEncoding.AppendLayout(s.encodeBuf[:0], r, s.l.config.TextLayout)
+	s.l.config.ConsoleOut.L.Print(string(s.encodeBuf))
+	return nil
+}
+
+func (s *consoleSink) Flush() error { return nil }
+func (s *consoleSink) Close() error { return nil }
@@ -0,0 +1,14 @@
+package logger
+
+import "fmt"
+
+// Errors reported to config.OnError wrap one of these along with the underlying cause, so they can
+// be told apart with errors.Is.
+var (
+	ErrInvalidPath = fmt.Errorf("blog: invalid path")
+	ErrWrite       = fmt.Errorf("blog: failed to write to log file")
+	ErrRotate      = fmt.Errorf("blog: failed to rotate log file")
+	ErrCompress    = fmt.Errorf("blog: failed to compress rotated log file")
+	ErrRetention   = fmt.Errorf("blog: failed to remove old log files")
+	ErrSink        = fmt.Errorf("blog: sink failed")
+)
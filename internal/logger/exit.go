@@ -0,0 +1,47 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+var (
+	exitMu    sync.Mutex
+	exitHooks []func()
+	exitOnce  sync.Once
+	osExit    = os.Exit // replaced in tests
+)
+
+// RegisterExitHook adds a function for Fatal to run before exiting the program, e.g. to flush another
+// logger or close a database. Hooks run in registration order, once, from the goroutine calling Fatal.
+func RegisterExitHook(f func()) {
+	exitMu.Lock()
+	defer exitMu.Unlock()
+	exitHooks = append(exitHooks, f)
+}
+
+// exit runs the exit hooks and exits the program with the given code. Only the first call does so,
+// concurrent calls block until the program is gone.
+func exit(code int) {
+	exitOnce.Do(func() {
+		exitMu.Lock()
+		hooks := append([]func(){}, exitHooks...)
+		exitMu.Unlock()
+		for _, hook := range hooks {
+			runExitHook(hook)
+		}
+		osExit(code)
+	})
+}
+
+// runExitHook runs a hook, reporting rather than propagating a panic so the program still exits
+// with the intended code.
+func runExitHook(hook func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(os.Stderr, "blog: exit hook panicked: %v\n", r)
+		}
+	}()
+	hook()
+}
@@ -1,21 +1,174 @@
 package logger
 
 import (
+	"bytes"
 	"fmt"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/Data-Corruption/blog/v3/internal/config"
+	LogLevel "github.com/Data-Corruption/blog/v3/internal/level"
+	"github.com/Data-Corruption/blog/v3/internal/record"
 	"github.com/Data-Corruption/blog/v3/internal/utils/strutil"
 )
 
-// fallbackToConsole disables file logging and enables console logging if not already enabled. Also passes the given error through.
+// fileSink is the built-in sink that buffers records and writes them to latest.log in the configured
+// directory, rotating it before a record would take it over the size limit. It reads its settings
+// from the logger's config, and on errors disables file logging and falls back to the console. The
+// file is held open between flushes and only reopened after a rotation, a change of path or name,
+// or an error.
+type fileSink struct {
+	l *Logger
+
+	// Buffer for messages before they are written to the file.
+	writeBuffer bytes.Buffer
+
+	// Length of each record in the write buffer, in order, so it can be split on record boundaries.
+	recordLens []int
+
+	// The open active log file, nil until the next write opens it, and its size as tracked in memory.
+	file *os.File
+	size int64
+
+	// Scratch space for encoding a single record, reused to avoid allocations.
+	encodeBuf []byte
+
+	// Recovery state after a failure made the logger fall back to the console. failedPath is the
+	// directory to retry, empty when not recovering. retryAt is when the run loop calls retry.
+	failedPath     string
+	failedAt       time.Time
+	retryAt        time.Time
+	retryAttempt   int  // reset by the first successful write after recovering
+	restoreConsole bool // the console was only enabled by the fallback
+
+	// Start of the rotation schedule period latest.log currently covers. Zero until the schedule is first checked.
+	periodStart time.Time
+
+	// Background compressions still running, and the paths they are compressing, which retention
+	// leaves alone until they are done.
+	compressing sync.WaitGroup
+	inFlight    sync.Map // path -> struct{}
+}
+
+// Write encodes the record into the write buffer, flushing it once it reaches the maximum size.
+func (s *fileSink) Write(r *record.Record) error {
+	if *s.l.config.DirectoryPath == "" || !s.l.config.FileLevel.Allows(r.Level) {
+		return nil
+	}
+	s.encodeBuf = s.l.config.FileEncoding.AppendLayout(s.encodeBuf[:0], r, s.l.config.TextLayout)
+	s.writeBuffer.Write(s.encodeBuf)
+	s.recordLens = append(s.recordLens, len(s.encodeBuf))
+	if s.writeBuffer.Len() >= *s.l.config.MaxBufferSizeBytes {
+		return s.Flush()
+	}
+	return nil
+}
+
+// Flush writes the buffered log to the filesystem and resets the buffer. The buffer is split on
+// record boundaries, rotating the file whenever the next record wouldn't fit, so no file goes over
+// the maximum size unless a single record does, which then gets a file of its own.
+// Failures are handled by falling back to the console, so it always returns nil.
+func (s *fileSink) Flush() error {
+	if (s.writeBuffer.Len() == 0) || (*s.l.config.DirectoryPath == "") {
+		return nil
+	}
+	for s.writeBuffer.Len() > 0 {
+		if err := s.openFile(); err != nil {
+			s.handleFlushError(fmt.Errorf("%w: %w", ErrWrite, err))
+			return nil
+		}
+		room := int64(*s.l.config.MaxFileSizeBytes) - s.size
+		if *s.l.config.ExternalRotation {
+			room = math.MaxInt64 // rotating is up to the external tool
+		}
+		n := s.fittingBytes(room)
+		if n == 0 && s.size > 0 {
+			if err := s.rotateLogFile(); err != nil {
+				s.handleFlushError(fmt.Errorf("%w: %w", ErrRotate, err))
+				return nil
+			}
+			continue
+		}
+		if n == 0 {
+			n = s.recordLens[0] // too large for any file, write it alone
+		}
+		if err := s.writeBuffered(n); err != nil {
+			s.handleFlushError(fmt.Errorf("%w: %w", ErrWrite, err))
+			return nil
+		}
+	}
+	return nil
+}
+
+// fittingBytes returns the length of the longest run of whole buffered records that fits in room bytes.
+func (s *fileSink) fittingBytes(room int64) int {
+	n := 0
+	for _, l := range s.recordLens {
+		if int64(n+l) > room {
+			break
+		}
+		n += l
+	}
+	return n
+}
+
+// Close flushes the buffer and closes the file.
+func (s *fileSink) Close() error {
+	s.Flush()
+	return s.closeFile()
+}
+
+// openFile opens the active log file for appending if it isn't open already, and reads its size.
+func (s *fileSink) openFile() error {
+	if s.file != nil {
+		return nil
+	}
+	f, err := os.OpenFile(s.getLatestPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	s.file, s.size = f, info.Size()
+	return nil
+}
+
+// reopen flushes and closes the active log file, so the next write opens whatever is at its path by
+// then. Used after an external tool has moved the file.
+func (s *fileSink) reopen() {
+	s.Flush()
+	if err := s.closeFile(); err != nil {
+		s.l.reportError(fmt.Errorf("%w: failed to close it: %w", ErrWrite, err))
+	}
+}
+
+// closeFile closes the active log file if open. The next write reopens it.
+func (s *fileSink) closeFile() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file, s.size = nil, 0
+	return err
+}
+
+// fallbackToConsole disables file logging and enables console logging, on the configured ConsoleWriter,
+// if not already enabled.
 func (l *Logger) fallbackToConsole() {
 	*l.config.DirectoryPath = ""
+	if l.file != nil {
+		l.file.closeFile()
+	}
 	if l.config.ConsoleOut.L == nil {
-		l.config.ConsoleOut = &config.ConsoleLogger{L: log.New(os.Stdout, "", 0)}
+		l.config.ConsoleOut = &config.ConsoleLogger{L: log.New(l.config.ConsoleWriter, "", 0)}
 	}
 }
 
@@ -31,106 +184,178 @@ func (l *Logger) setPath(path string) error {
 	fileInfo, err := os.Stat(cleanedPath)
 	if err != nil {
 		l.fallbackToConsole()
-		return fmt.Errorf("blog: failed to stat path: %w", err)
+		return fmt.Errorf("%w: %w", ErrInvalidPath, err)
 	}
 	if !fileInfo.IsDir() {
 		l.fallbackToConsole()
-		return fmt.Errorf("blog: path is not a directory: %s", cleanedPath)
+		return fmt.Errorf("%w: not a directory: %s", ErrInvalidPath, cleanedPath)
 	}
 	// Set the directory path
 	*l.config.DirectoryPath = cleanedPath
 	return nil
 }
 
-// getLatestPath returns the path to the latest.log file.
-func (l *Logger) getLatestPath() string {
-	return filepath.Join(*l.config.DirectoryPath, "latest.log")
+// getLatestPath returns the path to the active log file, latest.log by default.
+func (s *fileSink) getLatestPath() string {
+	return filepath.Join(*s.l.config.DirectoryPath, s.l.config.FileNaming.ActiveFile())
 }
 
-// rotatedFilename returns a new path for latest.log to be renamed to.
-func rotatedFilename(dir string) (string, error) {
-	timestamp := time.Now().Format("2006-01-02_15-04-05")
-	name := timestamp + ".log"
-	path := filepath.Join(dir, name)
-	if _, err := os.Stat(path); err == nil {
-		randomSuffix, err := strutil.Random(8)
-		if err != nil {
-			return "", err
+// rotatedFilename returns a new path for the active file to be renamed to, named for the given time.
+// Collisions with existing files, compressed or not, get a random or sequence suffix per the naming config.
+func (s *fileSink) rotatedFilename(t time.Time) (string, error) {
+	naming := *s.l.config.FileNaming
+	path := filepath.Join(*s.l.config.DirectoryPath, naming.RotatedFile(t, ""))
+	for attempt := 1; exists(path) || exists(path+".gz"); attempt++ {
+		suffix := strconv.Itoa(attempt)
+		if !naming.Sequence {
+			var err error
+			if suffix, err = strutil.Random(8); err != nil {
+				return "", err
+			}
 		}
-		name = fmt.Sprintf("%s_%s.log", timestamp, randomSuffix)
-		path = filepath.Join(dir, name)
+		path = filepath.Join(*s.l.config.DirectoryPath, naming.RotatedFile(t, suffix))
 	}
 	return path, nil
 }
 
-// handleFlushError prints the error to the console, sets use console to true and dir path to nil,
+// exists reports whether a file exists at path.
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// handleFlushError reports the error, sets use console to true and dir path to nil,
 // effectively disabling file logging, and prints the remaining write buffer to the console.
-func (l *Logger) handleFlushError(err error) {
-	l.fallbackToConsole()
+func (s *fileSink) handleFlushError(err error) {
+	s.startRecovery()
+	s.l.fallbackToConsole()
+	s.l.reportError(err)
 	// print the remaining write buffer to the console
-	l.config.ConsoleOut.L.Printf("failed to write to log file: %v", err)
-	l.config.ConsoleOut.L.Print(l.writeBuffer.String())
-	l.writeBuffer.Reset()
+	s.l.config.ConsoleOut.L.Print(s.writeBuffer.String())
+	s.writeBuffer.Reset()
+	s.recordLens = s.recordLens[:0]
 }
 
-func (l *Logger) rotateLogFile() error {
+func (s *fileSink) rotateLogFile() error {
 	// Get the new filename
-	path, err := rotatedFilename(*l.config.DirectoryPath)
+	path, err := s.rotatedFilename(time.Now())
 	if err != nil {
 		return fmt.Errorf("failed to get rotated filename: %w", err)
 	}
 	// Rename latest.log to the current timestamp
-	if err := os.Rename(l.getLatestPath(), path); err != nil {
-		return fmt.Errorf("failed to rename latest.log: %w", err)
-	}
-	// Create a new latest.log with the write buffer
-	if overflow, err := l.writeIfUnderMaxFileSize(); err != nil {
-		return fmt.Errorf("failed to write to latest.log: %w", err)
-	} else if overflow {
-		return fmt.Errorf("rotated log file is still too large")
+	s.closeFile()
+	if err := os.Rename(s.getLatestPath(), path); err != nil {
+		return fmt.Errorf("failed to rename the active log file: %w", err)
 	}
+	s.afterRotate(path)
 	return nil
 }
 
-// flush writes the buffered log to the filesystem and resets the buffer.
-func (l *Logger) flush() {
-	if (l.writeBuffer.Len() == 0) || (*l.config.DirectoryPath == "") {
+// afterRotate does the housekeeping for a freshly rotated file: compression and retention.
+func (s *fileSink) afterRotate(path string) {
+	if *s.l.config.Compress {
+		s.compressInBackground(path)
+	}
+	s.applyRetention()
+}
+
+// rotateOnSchedule rotates latest.log once the rotation schedule's period has moved on, naming the
+// rotated file for the start of the period it covers. The first call adopts a latest.log left over
+// from an earlier period, so it gets rotated right away.
+func (s *fileSink) rotateOnSchedule(now time.Time) {
+	schedule := *s.l.config.RotationSchedule
+	if !schedule.Enabled() || *s.l.config.ExternalRotation || *s.l.config.DirectoryPath == "" {
 		return
 	}
-	// write the buffer to the file
-	if overflow, err := l.writeIfUnderMaxFileSize(); err != nil {
-		l.handleFlushError(fmt.Errorf("blog: failed to write to log file: %w", err))
+	start := schedule.Start(now)
+	if s.periodStart.IsZero() {
+		s.periodStart = start
+		if info, err := os.Stat(s.getLatestPath()); err == nil {
+			s.periodStart = schedule.Start(info.ModTime())
+		}
+	}
+	if !start.After(s.periodStart) {
 		return
-	} else if overflow {
-		if err := l.rotateLogFile(); err != nil {
-			l.handleFlushError(fmt.Errorf("blog: failed to rotate log file: %w", err))
+	}
+	// Records buffered so far belong to the period that just ended. Flushing may disable file logging on errors.
+	s.Flush()
+	s.closeFile()
+	if info, err := os.Stat(s.getLatestPath()); err == nil && info.Size() > 0 && *s.l.config.DirectoryPath != "" {
+		path, err := s.rotatedFilename(s.periodStart)
+		if err == nil {
+			err = os.Rename(s.getLatestPath(), path)
+		}
+		if err != nil {
+			s.handleFlushError(fmt.Errorf("%w on schedule: %w", ErrRotate, err))
+		} else {
+			s.afterRotate(path)
 		}
 	}
+	s.periodStart = start
 }
 
-// write writes the buffered log to the file if the file is under the maximum size.
-// Returns true if the file was too large and needs to be rotated.
-func (l *Logger) writeIfUnderMaxFileSize() (bool, error) {
-	// Open the log file
-	f, err := os.OpenFile(l.getLatestPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+// writeBuffered writes the first n bytes of the write buffer, which must end on a record boundary,
+// to the open file and drops them from the buffer. The file is closed on errors so the next attempt
+// reopens it.
+func (s *fileSink) writeBuffered(n int) error {
+	written, err := s.file.Write(s.writeBuffer.Bytes()[:n])
+	s.size += int64(written)
 	if err != nil {
-		return false, fmt.Errorf("failed to open log file: %w", err)
+		s.closeFile()
+		return err
 	}
-	defer f.Close()
-	// Check if the log file is too large
-	fileInfo, err := f.Stat()
-	if err != nil {
-		return false, fmt.Errorf("failed to stat log file: %w", err)
+	s.writeBuffer.Next(n)
+	s.retryAttempt = 0
+	// Drop the written records' lengths
+	k := 0
+	for consumed := 0; consumed < n; k++ {
+		consumed += s.recordLens[k]
+	}
+	s.recordLens = s.recordLens[:copy(s.recordLens, s.recordLens[k:])]
+	return nil
+}
+
+// startRecovery schedules retries of the current directory per the recovery policy, before
+// fallbackToConsole clears it.
+func (s *fileSink) startRecovery() {
+	if !s.l.config.Recovery.Enabled() || *s.l.config.DirectoryPath == "" {
+		return
 	}
-	// If the log file is too large, return true
-	if fileInfo.Size() >= int64(*l.config.MaxFileSizeBytes) {
-		return true, nil
+	s.failedPath = *s.l.config.DirectoryPath
+	s.failedAt = time.Now()
+	s.restoreConsole = s.l.config.ConsoleOut.L == nil
+	s.retryAt = s.failedAt.Add(s.l.config.Recovery.Delay(s.retryAttempt))
+}
+
+// stopRecovery cancels any pending retry, e.g. when the directory is changed by hand.
+func (s *fileSink) stopRecovery() {
+	s.failedPath, s.retryAt = "", time.Time{}
+}
+
+// retry probes the directory file logging failed in by opening the log file. On success file
+// logging resumes, starting with a record of the outage whatever the level, otherwise the next
+// retry is scheduled with a longer delay. Opening isn't enough to reset the delay, only a write is,
+// so a directory that opens but fails again keeps backing off.
+func (s *fileSink) retry() {
+	if s.failedPath == "" {
+		return
+	}
+	*s.l.config.DirectoryPath = s.failedPath
+	s.retryAttempt++
+	if err := s.openFile(); err != nil {
+		*s.l.config.DirectoryPath = ""
+		s.retryAt = time.Now().Add(s.l.config.Recovery.Delay(s.retryAttempt))
+		return
 	}
-	// Write the buffered log to the file
-	if _, err := f.Write(l.writeBuffer.Bytes()); err != nil {
-		return false, fmt.Errorf("failed to write to log file: %w", err)
+	now := time.Now()
+	s.l.handleMessage(LogMessage{Record: record.Record{
+		Time:    now,
+		Level:   LogLevel.WARN,
+		Message: "file logging resumed",
+		Fields:  []record.Field{record.Time("outage_start", s.failedAt), record.Duration("outage", now.Sub(s.failedAt))},
+	}, overridden: true})
+	if s.restoreConsole {
+		s.l.config.ConsoleOut.L = nil
 	}
-	// Reset the buffer
-	l.writeBuffer.Reset()
-	return false, nil
+	s.stopRecovery()
 }
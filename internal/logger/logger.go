@@ -1,88 +1,142 @@
 package logger
 
 import (
-	"bytes"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/Data-Corruption/blog/v3/internal/config"
 	LogLevel "github.com/Data-Corruption/blog/v3/internal/level"
+	"github.com/Data-Corruption/blog/v3/internal/record"
+	"github.com/Data-Corruption/blog/v3/internal/sink"
 	"github.com/Data-Corruption/blog/v3/internal/utils"
-	"github.com/Data-Corruption/blog/v3/internal/utils/strutil"
 )
 
 /*
 Logger is a simple, thread-safe logger. It supports various log levels, file and or
 console logging, basic performance tuning, automatic flushing, and size based log rotation.
+
+Child loggers created with With and Named share their parent's core, meaning the same goroutine,
+buffer and files, and only add a name and bound fields to the records they produce.
 */
 type Logger struct {
+	*core
+	name   string         // component name stamped on every record, e.g. "db.pool". Empty for the root logger.
+	fields []record.Field // fields bound with With, placed before each message's own fields.
+}
+
+// core is the state shared by a logger and all of its children.
+type core struct {
 	// Configuration settings.
 	config *config.Config
 
-	// Number of stack frames to skip when including the location of the log message. Default is 2, -1 to disable.
-	locationSkip int // not configurable after creation for performance reasons
+	// Copy of the configured level readable from any goroutine, kept in sync by NewLogger and UpdateConfig.
+	level atomic.Int64
+
+	// Copy of the configured overflow policy, read when the message channel is full. Kept in sync like level.
+	overflow atomic.Pointer[config.Overflow]
+
+	// Compiled config.VModule overrides, nil when there are none. Kept in sync like level.
+	vmodule atomic.Pointer[vmodule]
 
-	// Buffer for messages before they are written to console or file.
-	writeBuffer bytes.Buffer
+	// Messages dropped by the overflow policy, in total and since the last "messages dropped" record.
+	dropped    atomic.Uint64
+	unreported atomic.Uint64
+
+	// Number of stack frames to skip to find the caller of a log function, for its location and VModule
+	// file overrides, and whether records include the location. Not configurable after creation for
+	// performance reasons.
+	callerSkip int
+	location   bool
+
+	// Outputs for records. The built-in file and console sinks followed by config.Sinks.
+	sinks []sink.Sink
+	file  *fileSink // the built-in file sink, also found in sinks
 
 	// True when the goroutine is running.
 	Running      bool
 	RunningMutex sync.Mutex
 
-	// Config update method. Uses chans instead of a mutex for better performance.
+	// Config update method. Uses chans instead of a mutex for better performance. updateMutex only
+	// orders concurrent UpdateConfig calls, so the copies above are stored in the order applied.
+	updateMutex   sync.Mutex
 	getConfigChan chan chan config.Config
 	setConfigChan chan config.Config // nil fields are ignored
 
-	messageChan   chan LogMessage
-	flushSignal   chan struct{}
-	syncFlushChan chan chan struct{}
-	shutdownChan  chan chan struct{}
+	messageChan     chan LogMessage
+	keptChan        chan LogMessage // FATAL messages DropOldest took from the head of messageChan, handled first
+	compressErrChan chan error      // failures from background compression, reported by the logger goroutine
+	flushSignal     chan struct{}
+	reopenSignal    chan struct{}
+	syncFlushChan   chan chan struct{}
+	shutdownChan    chan shutdownRequest
+}
+
+// shutdownRequest asks the logger goroutine to handle the messages already queued, until the
+// deadline if not zero, then close the sinks and stop. It sends the number of queued messages
+// lost to the deadline on done.
+type shutdownRequest struct {
+	deadline time.Time
+	done     chan int
 }
 
 // LogMessage represents a single log message.
 type LogMessage struct {
-	level     LogLevel.LogLevel
-	exitCode  int // only used by FATAL messages
-	timestamp time.Time
-	location  string // e.g., "file.go:42"
-	content   string
+	record.Record
+	ack        chan struct{} // closed once the message is flushed, only set by Fatal
+	overridden bool          // passed a VModule override, so the configured level doesn't apply
 }
 
 // NewLogger creates a new Logger instance with the provided configuration.
 // It initializes all channels and starts the background logging goroutine.
 //
 // The msgChanSize parameter controls the buffer size of the message channel,
-// where 0 means unbuffered. LocationSkip controls the number of stack frames
-// to skip when including the location in log messages (-1 to disable). For
-// normal usage, LocationSkip should be set to 2. Location information is only
-// included for ERROR, DEBUG, and FATAL log levels.
+// where 0 means unbuffered. CallerSkip controls the number of stack frames
+// to skip when finding the caller of a log function, for its location and
+// VModule file overrides. For normal usage, CallerSkip should be set to 2.
+// IncludeLocation adds the location to log messages, which is only done for
+// ERROR, DEBUG, TRACE and FATAL log levels.
 //
 // Returns an error if the log directory path cannot be set.
-func NewLogger(cfg *config.Config, msgChanSize int, LocationSkip int) (*Logger, error) {
+func NewLogger(cfg *config.Config, msgChanSize int, CallerSkip int, IncludeLocation bool) (*Logger, error) {
 	// Create the logger instance.
-	l := &Logger{
-		config:        cfg,
-		locationSkip:  LocationSkip,
-		Running:       true,
-		messageChan:   make(chan LogMessage, msgChanSize),
-		getConfigChan: make(chan chan config.Config),
-		setConfigChan: make(chan config.Config),
-		flushSignal:   make(chan struct{}),
-		syncFlushChan: make(chan chan struct{}),
-		shutdownChan:  make(chan chan struct{}),
-	}
+	l := &Logger{core: &core{
+		config:          cfg,
+		callerSkip:      CallerSkip,
+		location:        IncludeLocation,
+		Running:         true,
+		messageChan:     make(chan LogMessage, msgChanSize),
+		keptChan:        make(chan LogMessage, msgChanSize),
+		compressErrChan: make(chan error, 16),
+		getConfigChan:   make(chan chan config.Config),
+		setConfigChan:   make(chan config.Config),
+		flushSignal:     make(chan struct{}),
+		reopenSignal:    make(chan struct{}),
+		syncFlushChan:   make(chan chan struct{}),
+		shutdownChan:    make(chan shutdownRequest),
+	}}
 
 	// Apply default values to the configuration.
 	l.config.ApplyDefaults()
+	l.level.Store(int64(*l.config.Level))
+	l.overflow.Store(utils.Ptr(*l.config.Overflow))
+	l.vmodule.Store(newVModule(*l.config.VModule))
+	l.file = &fileSink{l: l}
+	l.sinks = append([]sink.Sink{l.file, &consoleSink{l: l}}, l.config.Sinks...)
 
 	// Set the log directory path
 	if err := l.setPath(*l.config.DirectoryPath); err != nil {
 		return nil, err
 	}
+	l.file.recoverCompression()
+	l.file.applyRetention()
 
 	// Start the logger goroutine
 	go l.run()
@@ -91,33 +145,59 @@ func NewLogger(cfg *config.Config, msgChanSize int, LocationSkip int) (*Logger,
 	return l, nil
 }
 
-// Shutdown synchronously flushes and waits for the logger to shutdown it's goroutine for the given timeout duration.
-// A timeout of 0 means block indefinitely.
-// You may want to time.Sleep(20 * time.Millisecond) before calling this function to ensure all log messages are buffered.
+// Shutdown stops the logger goroutine after it has handled every message already queued, then
+// flushes and closes the sinks. A timeout of 0 means block indefinitely. Otherwise queued messages
+// not handled in time are lost, and an error reports how many. If the goroutine is too busy to take
+// the request in time, it keeps running and the error says so, Shutdown can be called again.
 func (l *Logger) Shutdown(timeout time.Duration) error {
-	done := make(chan struct{})
-	l.shutdownChan <- done
-	if timeout == 0 {
-		<-done
-		return nil
+	req := shutdownRequest{done: make(chan int, 1)}
+	var expired <-chan time.Time
+	if timeout > 0 {
+		req.deadline = time.Now().Add(timeout)
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
 	}
 	select {
-	case <-done:
-	case <-time.After(timeout):
+	case l.shutdownChan <- req:
+	case <-expired:
+		// The goroutine never took the request, so it keeps running and will still handle the queue.
+		return fmt.Errorf("logger failed to shutdown in time, it is still running with %d queued messages", len(l.messageChan))
+	}
+	select {
+	case lost := <-req.done:
+		if lost > 0 {
+			return fmt.Errorf("logger failed to shutdown in time, %d queued messages lost", lost)
+		}
+		return nil
+	case <-expired:
+		return fmt.Errorf("logger failed to shutdown in time, %d queued messages lost", len(l.messageChan))
 	}
-	l.RunningMutex.Lock()
-	defer l.RunningMutex.Unlock()
-	return utils.Ternary(l.Running, fmt.Errorf("logger failed to shutdown in time"), nil)
 }
 
-// Start restarts the logger goroutine after a shutdown.
-func (l *Logger) Start() {
+// drain handles the messages queued when called, until the deadline if not zero. Returns the
+// number of them left unhandled.
+func (l *Logger) drain(deadline time.Time) int {
+	l.handleKept()
+	queued := len(l.messageChan)
+	for i := 0; i < queued; i++ {
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return queued - i
+		}
+		l.handleMessage(<-l.messageChan)
+	}
+	return 0
+}
+
+// Start does nothing while the logger goroutine is running. Once Shutdown has stopped it the sinks
+// are closed, so it returns an error rather than restart, create a new Logger instead.
+func (l *Logger) Start() error {
 	l.RunningMutex.Lock()
 	defer l.RunningMutex.Unlock()
 	if !l.Running {
-		l.Running = true
-		go l.run()
+		return fmt.Errorf("blog: logger has been shut down and its sinks closed, it can't be restarted")
 	}
+	return nil
 }
 
 // Flush asynchronously flushes the log write buffer.
@@ -125,6 +205,12 @@ func (l *Logger) Flush() {
 	l.flushSignal <- struct{}{}
 }
 
+// Reopen flushes and closes the log file, reopening it at its path on the next write.
+// Call it after an external tool has moved the file, SIGHUP does the same with ExternalRotation.
+func (l *Logger) Reopen() {
+	l.reopenSignal <- struct{}{}
+}
+
 // SyncFlush synchronously flushes the log write buffer with the given timeout duration.
 // A timeout of 0 means block indefinitely.
 func (l *Logger) SyncFlush(timeout time.Duration) {
@@ -146,94 +232,346 @@ func (l *Logger) GetConfigCopy() config.Config {
 // UpdateConfig updates the logger configuration with the provided settings.
 // Nil fields are ignored.
 func (l *Logger) UpdateConfig(cfg config.Config) {
+	l.updateMutex.Lock()
+	defer l.updateMutex.Unlock()
+	if cfg.Level != nil {
+		l.level.Store(int64(*cfg.Level))
+	}
+	if cfg.Overflow != nil {
+		l.overflow.Store(utils.Ptr(*cfg.Overflow))
+	}
+	if cfg.VModule != nil {
+		l.vmodule.Store(newVModule(*cfg.VModule))
+	}
 	l.setConfigChan <- cfg
 }
 
+// With returns a child logger that adds the given fields to every message it logs.
+// Fields may be record.Field values or alternating key/value pairs, as with Info.
+func (l *Logger) With(fields ...any) *Logger {
+	child := *l
+	child.fields = append(l.fields[:len(l.fields):len(l.fields)], record.FromArgs(fields)...)
+	return &child
+}
+
+// Named returns a child logger with the given component name appended to this logger's name,
+// separated by a dot. Example: l.Named("db").Named("pool") logs with the name "db.pool".
+func (l *Logger) Named(name string) *Logger {
+	child := *l
+	child.name = utils.Ternary(l.name == "", name, l.name+"."+name)
+	return &child
+}
+
 // Log message functions. These are the main interface for logging messages.
+// Fields may be record.Field values or alternating key/value pairs, e.g. l.Info("login", "user", id).
+
+func (l *Logger) Info(msg string, fields ...any)    { l.qM(LogLevel.INFO, nil, fields, "%s", msg) }
+func (l *Logger) Infof(format string, args ...any)  { l.qM(LogLevel.INFO, nil, nil, format, args...) }
+func (l *Logger) Warn(msg string, fields ...any)    { l.qM(LogLevel.WARN, nil, fields, "%s", msg) }
+func (l *Logger) Warnf(format string, args ...any)  { l.qM(LogLevel.WARN, nil, nil, format, args...) }
+func (l *Logger) Error(msg string, fields ...any)   { l.qM(LogLevel.ERROR, nil, fields, "%s", msg) }
+func (l *Logger) Errorf(format string, args ...any) { l.qM(LogLevel.ERROR, nil, nil, format, args...) }
+func (l *Logger) Debug(msg string, fields ...any)   { l.qM(LogLevel.DEBUG, nil, fields, "%s", msg) }
+func (l *Logger) Debugf(format string, args ...any) { l.qM(LogLevel.DEBUG, nil, nil, format, args...) }
+func (l *Logger) Trace(msg string, fields ...any)   { l.qM(LogLevel.TRACE, nil, fields, "%s", msg) }
+func (l *Logger) Tracef(format string, args ...any) { l.qM(LogLevel.TRACE, nil, nil, format, args...) }
 
-func (l *Logger) Info(msg string)                   { l.qM(LogLevel.INFO, 0, "%s", msg) }
-func (l *Logger) Infof(format string, args ...any)  { l.qM(LogLevel.INFO, 0, format, args...) }
-func (l *Logger) Warn(msg string)                   { l.qM(LogLevel.WARN, 0, "%s", msg) }
-func (l *Logger) Warnf(format string, args ...any)  { l.qM(LogLevel.WARN, 0, format, args...) }
-func (l *Logger) Error(msg string)                  { l.qM(LogLevel.ERROR, 0, "%s", msg) }
-func (l *Logger) Errorf(format string, args ...any) { l.qM(LogLevel.ERROR, 0, format, args...) }
-func (l *Logger) Debug(msg string)                  { l.qM(LogLevel.DEBUG, 0, "%s", msg) }
-func (l *Logger) Debugf(format string, args ...any) { l.qM(LogLevel.DEBUG, 0, format, args...) }
-
-// Fatal attempts to log a message and exits the program. It exits with the given exit code either when the message is
-// logged or the timeout duration is reached. A timeout of 0 means block indefinitely.
-func (l *Logger) Fatal(exitCode int, timeout time.Duration, msg string) {
-	l.qM(LogLevel.FATAL, exitCode, "%s", msg)
-	time.Sleep(timeout)
-	fmt.Printf("Fatal message failed to log in time: %s\n", msg)
-	os.Exit(exitCode)
-}
-
-// Fatalf is a convenience function that calls Fatal with a format string.
+// Log logs a message at any severity, e.g. one added with LogLevel.Register. FATAL is logged like any
+// other level, the program does not exit, use Fatal for that. The thresholds NONE and ALL are not
+// severities, messages logged with them are ignored.
+func (l *Logger) Log(lvl LogLevel.LogLevel, msg string, fields ...any) {
+	if !lvl.IsThreshold() {
+		l.qM(lvl, nil, fields, "%s", msg)
+	}
+}
+func (l *Logger) Logf(lvl LogLevel.LogLevel, format string, args ...any) {
+	if !lvl.IsThreshold() {
+		l.qM(lvl, nil, nil, format, args...)
+	}
+}
+
+// Fatal logs a message and exits the program with the given exit code as soon as the message has been
+// flushed, or once the timeout is reached. A timeout of 0 means block indefinitely. Exit hooks run
+// before exiting, see RegisterExitHook.
+func (l *Logger) Fatal(exitCode int, timeout time.Duration, msg string, fields ...any) {
+	ack := make(chan struct{})
+	queued := l.qM(LogLevel.FATAL, ack, fields, "%s", msg)
+	l.waitAndExit(exitCode, timeout, queued, ack, "%s", msg)
+}
+
+// Fatalf is like Fatal with a format string.
 func (l *Logger) Fatalf(exitCode int, timeout time.Duration, format string, args ...any) {
-	l.Fatal(exitCode, timeout, fmt.Sprintf(format, args...))
+	ack := make(chan struct{})
+	queued := l.qM(LogLevel.FATAL, ack, nil, format, args...) // called directly so the location is the caller's
+	l.waitAndExit(exitCode, timeout, queued, ack, format, args...)
+}
+
+// waitAndExit waits for a queued fatal message to be flushed, or the timeout, then exits.
+func (l *Logger) waitAndExit(exitCode int, timeout time.Duration, queued bool, ack chan struct{}, format string, args ...any) {
+	if queued {
+		var expired <-chan time.Time
+		if timeout > 0 {
+			expired = time.After(timeout)
+		}
+		select {
+		case <-ack:
+		case <-expired:
+			fmt.Fprintf(os.Stderr, "blog: fatal message failed to log in time: %s\n", fmt.Sprintf(format, args...))
+		}
+	}
+	exit(exitCode)
 }
 
 // Internal functions
 
-// qM is a helper function to create and enqueue a log message.
-func (l *Logger) qM(lvl LogLevel.LogLevel, exitCode int, format string, args ...any) {
+// qM is a helper function to create and enqueue a log message, returning whether it did.
+// Messages filtered by the current level return before any formatting or caller lookup. With VModule
+// file overrides the caller is looked up to find the one that applies, which is cached per call site,
+// unless a rule already matched the logger name.
+func (l *Logger) qM(lvl LogLevel.LogLevel, ack chan struct{}, fields []any, format string, args ...any) bool {
+	if !l.Enabled(lvl) {
+		return false
+	}
+	var pc uintptr
+	overridden := false
+	if v := l.vmodule.Load(); v != nil {
+		threshold, ok := v.nameLevel(l.name)
+		if !ok && len(v.files) > 0 {
+			pc = l.callerPC()
+			threshold, ok = v.fileLevel(pc)
+		}
+		var allowed bool
+		if allowed, overridden = l.allows(lvl, threshold, ok); !allowed {
+			return false
+		}
+	}
 	m := LogMessage{
-		level:     lvl,
-		exitCode:  exitCode,
-		timestamp: time.Now(),
-		location:  "",
-		content:   fmt.Sprintf(format, args...),
-	}
-	if l.locationSkip != -1 {
-		if (lvl == LogLevel.FATAL) || (lvl == LogLevel.ERROR) || (lvl == LogLevel.DEBUG) {
-			if _, file, line, ok := runtime.Caller(l.locationSkip); ok {
-				m.location = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+		Record: record.Record{
+			Time:    time.Now(),
+			Level:   lvl,
+			Name:    l.name,
+			Message: fmt.Sprintf(format, args...),
+			Fields:  l.boundFields(record.FromArgs(fields)),
+		},
+		ack:        ack,
+		overridden: overridden,
+	}
+	if l.location && includeLocation(lvl) {
+		if pc == 0 {
+			pc = l.callerPC()
+		}
+		if frame, _ := runtime.CallersFrames([]uintptr{pc}).Next(); frame.File != "" {
+			m.Location = fmt.Sprintf("%s:%d", filepath.Base(frame.File), frame.Line)
+		}
+	}
+	l.enqueue(m)
+	return true
+}
+
+// enqueue sends the message to the logger goroutine, applying the overflow policy when the
+// message channel is full.
+func (l *Logger) enqueue(m LogMessage) {
+	o := l.overflow.Load()
+	if o.Keeps(m.Level) {
+		l.messageChan <- m
+		return
+	}
+	select {
+	case l.messageChan <- m:
+		return
+	default:
+	}
+	if o.Policy == config.DropOldest && cap(l.messageChan) > 0 {
+		for {
+			select {
+			case old := <-l.messageChan:
+				if o.Keeps(old.Level) {
+					// Never drop a FATAL message. Hand it over ahead of the queue and drop its neighbour instead.
+					l.keptChan <- old
+					continue
+				}
+				l.drop()
+			default: // drained by the logger goroutine meanwhile
+			}
+			select {
+			case l.messageChan <- m:
+				return
+			default:
 			}
 		}
 	}
-	l.messageChan <- m
+	l.drop()
+}
+
+// handleKept handles the messages DropOldest took from the queue but had to keep, which are older
+// than any message still queued.
+func (l *Logger) handleKept() {
+	for {
+		select {
+		case m := <-l.keptChan:
+			l.handleMessage(m)
+		default:
+			return
+		}
+	}
+}
+
+// drop counts a message dropped by the overflow policy.
+func (l *Logger) drop() {
+	l.dropped.Add(1)
+	l.unreported.Add(1)
+}
+
+// Dropped returns the total number of messages dropped by the overflow policy.
+func (l *Logger) Dropped() uint64 {
+	return l.dropped.Load()
+}
+
+// reportDropped logs how many messages were dropped since the last report, whatever the configured
+// level. Called once the message channel has drained, so the record isn't lost to the same pressure.
+func (l *Logger) reportDropped() {
+	if l.unreported.Load() == 0 {
+		return
+	}
+	n := l.unreported.Swap(0)
+	l.handleMessage(LogMessage{Record: record.Record{
+		Time:    time.Now(),
+		Level:   LogLevel.WARN,
+		Message: fmt.Sprintf("%d messages dropped", n),
+		Fields:  []record.Field{record.Uint64("dropped", n)},
+	}, overridden: true})
+}
+
+// boundFields returns the logger's bound fields followed by the given fields.
+func (l *Logger) boundFields(fields []record.Field) []record.Field {
+	if len(l.fields) == 0 {
+		return fields
+	}
+	return append(l.fields[:len(l.fields):len(l.fields)], fields...)
+}
+
+// includeLocation reports whether messages of the given level carry their source location.
+func includeLocation(lvl LogLevel.LogLevel) bool {
+	return lvl >= LogLevel.ERROR || lvl <= LogLevel.DEBUG
+}
+
+// Enabled reports whether a message of the given level passes the VModule override matching the logger
+// name, or else the current level, or could pass one of the file overrides, which depend on the call
+// site. Safe to call from any goroutine, and cheap enough to guard the construction of expensive log
+// arguments.
+func (l *Logger) Enabled(lvl LogLevel.LogLevel) bool {
+	v := l.vmodule.Load()
+	if v == nil {
+		return LogLevel.LogLevel(l.level.Load()).Allows(lvl)
+	}
+	if threshold, ok := v.nameLevel(l.name); ok {
+		return threshold.Allows(lvl)
+	}
+	return LogLevel.LogLevel(l.level.Load()).Allows(lvl) || v.fileMin.Allows(lvl)
 }
 
 func (l *Logger) handleMessage(m LogMessage) {
+	if m.ack != nil {
+		defer close(m.ack) // also when filtered, so Fatal doesn't wait out its timeout
+	}
 	// Check if the message should be logged given the current log level
-	if l.config.Level == nil || *l.config.Level == LogLevel.NONE {
+	if !m.overridden && !l.config.Level.Allows(m.Level) {
 		return
 	}
-	if m.level > *l.config.Level {
-		return
+	// Hand the message to every sink, each may filter further
+	for _, s := range l.sinks {
+		if err := s.Write(&m.Record); err != nil {
+			l.reportError(fmt.Errorf("%w to write: %w", ErrSink, err))
+		}
 	}
-	// Create the message prefix
-	prefix := m.timestamp.Format("[2006-01-02,15-04-05,") + m.level.String() + "] "
-	prefix = strutil.Pad(prefix, 28)
-	// Add location if it exists
-	if m.location != "" {
-		prefix += "[" + m.location + "] "
-	}
-	// Format the message
-	m.content = prefix + m.content + "\n"
-	// If file logging is enabled, write the message to the log file
-	if *l.config.DirectoryPath != "" {
-		l.writeBuffer.WriteString(m.content)
-		if l.writeBuffer.Len() >= *l.config.MaxBufferSizeBytes {
-			l.flush()
+	if m.ack != nil {
+		l.flush()
+	}
+}
+
+// flush flushes every sink.
+func (l *Logger) flush() {
+	for _, s := range l.sinks {
+		if err := s.Flush(); err != nil {
+			l.reportError(fmt.Errorf("%w to flush: %w", ErrSink, err))
+		}
+	}
+}
+
+// closeSinks flushes and closes every sink. Background compression, including of a file the last
+// flush rotated, is waited for until the deadline if not zero, and its errors reported first.
+func (l *Logger) closeSinks(deadline time.Time) {
+	if err := l.file.Flush(); err != nil {
+		l.reportError(fmt.Errorf("%w to flush: %w", ErrSink, err))
+	}
+	l.file.waitCompression(deadline)
+	for pending := true; pending; {
+		select {
+		case err := <-l.compressErrChan:
+			l.reportError(err)
+		default:
+			pending = false
 		}
 	}
-	// If console logging is enabled, write the message to the console
+	for _, s := range l.sinks {
+		if err := s.Close(); err != nil {
+			l.reportError(fmt.Errorf("%w to close: %w", ErrSink, err))
+		}
+	}
+}
+
+// setSinks replaces the additional sinks. Sinks that are no longer present are closed.
+func (l *Logger) setSinks(extra []sink.Sink) {
+	for _, old := range l.config.Sinks {
+		if !containsSink(extra, old) {
+			if err := old.Close(); err != nil {
+				l.reportError(fmt.Errorf("%w to close: %w", ErrSink, err))
+			}
+		}
+	}
+	l.config.Sinks = append([]sink.Sink(nil), extra...)
+	l.sinks = append(l.sinks[:2:2], l.config.Sinks...) // keep the built-in file and console sinks
+}
+
+// containsSink reports whether list contains s. Sinks of uncomparable types never match.
+func containsSink(list []sink.Sink, s sink.Sink) bool {
+	t := reflect.TypeOf(s)
+	for _, x := range list {
+		if reflect.TypeOf(x) == t && t.Comparable() && x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// reportError prints an internal error to the console, or to stderr when the console is disabled,
+// and passes it to the OnError hook if set.
+func (l *Logger) reportError(err error) {
 	if l.config.ConsoleOut.L != nil {
-		l.config.ConsoleOut.L.Print(m.content)
+		l.config.ConsoleOut.L.Print(err)
+	} else {
+		fmt.Fprintln(os.Stderr, err)
 	}
-	if m.level == LogLevel.FATAL {
-		l.flush()
-		os.Exit(m.exitCode)
+	if l.config.OnError != nil {
+		l.config.OnError(err)
 	}
 }
 
 // run is the main loop for the logger goroutine.
 func (l *Logger) run() {
-	ticker := time.NewTicker(*l.config.FlushInterval)
-	restartTickerReq := false
+	ticker := time.NewTicker(time.Hour)
+	restartTickerReq := true // applies the configured interval, which may be 0 (disabled)
 	defer ticker.Stop()
+	rotateTimer := time.NewTimer(time.Hour)
+	restartRotateReq := true // checks the schedule right away and arms the timer for the next boundary
+	defer rotateTimer.Stop()
+	retryTimer := time.NewTimer(time.Hour)
+	var retryAt time.Time // when retryTimer is armed for, zero when stopped
+	retryTimer.Stop()
+	defer retryTimer.Stop()
+	hup := make(chan os.Signal, 1)
+	restartHupReq := true // listens for SIGHUP while external rotation is enabled
+	defer signal.Stop(hup)
 
 	for {
 		if restartTickerReq {
@@ -243,39 +581,136 @@ func (l *Logger) run() {
 				ticker = time.NewTicker(*l.config.FlushInterval)
 			}
 		}
+		if restartRotateReq {
+			restartRotateReq = false
+			rotateTimer.Stop()
+			if l.config.RotationSchedule.Enabled() && !*l.config.ExternalRotation {
+				now := time.Now()
+				l.file.rotateOnSchedule(now)
+				rotateTimer = time.NewTimer(l.config.RotationSchedule.Next(now).Sub(now))
+			}
+		}
+		if !l.file.retryAt.Equal(retryAt) {
+			retryAt = l.file.retryAt
+			retryTimer.Stop()
+			if !retryAt.IsZero() {
+				retryTimer = time.NewTimer(time.Until(retryAt))
+			}
+		}
+		if restartHupReq {
+			restartHupReq = false
+			if *l.config.ExternalRotation {
+				signal.Notify(hup, syscall.SIGHUP)
+			} else {
+				signal.Stop(hup)
+			}
+		}
 		select {
+		case m := <-l.keptChan:
+			l.handleMessage(m)
 		case m := <-l.messageChan:
+			l.handleKept() // taken from ahead of m
 			l.handleMessage(m)
+			if len(l.messageChan) == 0 {
+				l.reportDropped()
+			}
 		case <-l.flushSignal:
 			l.flush()
+		case <-retryTimer.C:
+			l.file.retry()
+		case <-l.reopenSignal:
+			l.file.reopen()
+		case <-hup:
+			l.file.reopen()
 		case <-ticker.C:
 			l.flush()
+		case <-rotateTimer.C:
+			restartRotateReq = true
+		case err := <-l.compressErrChan:
+			l.reportError(err)
 		case done := <-l.syncFlushChan:
 			l.flush()
 			done <- struct{}{}
-		case done := <-l.shutdownChan:
-			l.flush()
-			done <- struct{}{}
+		case req := <-l.shutdownChan:
+			lost := l.drain(req.deadline)
+			if lost > 0 {
+				l.reportError(fmt.Errorf("blog: %d queued messages lost at shutdown", lost))
+			}
+			l.reportDropped()
+			l.closeSinks(req.deadline)
 			l.RunningMutex.Lock()
 			l.Running = false
 			l.RunningMutex.Unlock()
+			req.done <- lost
 			return
 		case resp := <-l.getConfigChan:
 			resp <- *l.config
 		case cfg := <-l.setConfigChan:
 			utils.CopyIfNotNil(l.config.Level, cfg.Level)
+			utils.CopyIfNotNil(l.config.VModule, cfg.VModule)
 			utils.CopyIfNotNil(l.config.MaxBufferSizeBytes, cfg.MaxBufferSizeBytes)
 			utils.CopyIfNotNil(l.config.MaxFileSizeBytes, cfg.MaxFileSizeBytes)
+			utils.CopyIfNotNil(l.config.Overflow, cfg.Overflow)
+			utils.CopyIfNotNil(l.config.FileEncoding, cfg.FileEncoding)
+			utils.CopyIfNotNil(l.config.FileLevel, cfg.FileLevel)
+			utils.CopyIfNotNil(l.config.ConsoleLevel, cfg.ConsoleLevel)
+			utils.CopyIfNotNil(l.config.ConsoleEncoding, cfg.ConsoleEncoding)
+			utils.CopyIfNotNil(l.config.TextLayout, cfg.TextLayout)
 			if cfg.FlushInterval != nil {
 				*l.config.FlushInterval = *cfg.FlushInterval
 				restartTickerReq = true
 			}
+			if cfg.RotationSchedule != nil {
+				*l.config.RotationSchedule = *cfg.RotationSchedule
+				l.file.periodStart = time.Time{}
+				restartRotateReq = true
+			}
+			if cfg.Recovery != nil {
+				*l.config.Recovery = *cfg.Recovery
+				if !cfg.Recovery.Enabled() {
+					l.file.stopRecovery()
+				}
+			}
 			if cfg.DirectoryPath != nil {
-				l.setPath(*cfg.DirectoryPath)
+				l.file.stopRecovery()
+				l.file.closeFile()
+				if err := l.setPath(*cfg.DirectoryPath); err != nil {
+					l.reportError(err)
+				} else {
+					l.file.recoverCompression()
+				}
+				l.file.periodStart = time.Time{}
+				restartRotateReq = true
+			}
+			if cfg.Retention != nil {
+				*l.config.Retention = *cfg.Retention
+			}
+			if cfg.FileNaming != nil {
+				l.file.Flush() // finish the old active file first
+				l.file.closeFile()
+				*l.config.FileNaming = *cfg.FileNaming
+			}
+			utils.CopyIfNotNil(l.config.Compress, cfg.Compress)
+			if cfg.ExternalRotation != nil {
+				*l.config.ExternalRotation = *cfg.ExternalRotation
+				restartHupReq = true
+				restartRotateReq = true
+			}
+			if cfg.DirectoryPath != nil || cfg.Retention != nil || cfg.FileNaming != nil {
+				l.file.applyRetention()
+			}
+			if cfg.ConsoleWriter != nil {
+				l.config.ConsoleWriter = cfg.ConsoleWriter
 			}
 			if cfg.ConsoleOut != nil {
 				l.config.ConsoleOut.L = cfg.ConsoleOut.L
 			}
+			if cfg.OnError != nil {
+				l.config.OnError = cfg.OnError
+			}
+			if cfg.Sinks != nil {
+				l.setSinks(cfg.Sinks)
+			}
 		}
 	}
 }
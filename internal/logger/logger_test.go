@@ -2,15 +2,31 @@ package logger
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/Data-Corruption/blog/v3/internal/config"
+	"github.com/Data-Corruption/blog/v3/internal/format"
 	LogLevel "github.com/Data-Corruption/blog/v3/internal/level"
+	"github.com/Data-Corruption/blog/v3/internal/record"
+	"github.com/Data-Corruption/blog/v3/internal/sink"
+	"github.com/Data-Corruption/blog/v3/internal/utils"
 )
 
 // helper to return pointer values for simple types.
@@ -25,7 +41,7 @@ func TestLoggerConsole(t *testing.T) {
 		Level:         ptr(LogLevel.INFO),
 		ConsoleOut:    &config.ConsoleLogger{L: log.New(buf, "", 0)},
 	}
-	logInst, err := NewLogger(config, 255, 2)
+	logInst, err := NewLogger(config, 255, 2, true)
 	if err != nil {
 		t.Fatalf("failed to create logger: %v", err)
 	}
@@ -59,7 +75,7 @@ func TestLoggerFile(t *testing.T) {
 		Level:         ptr(LogLevel.INFO),
 		ConsoleOut:    &config.ConsoleLogger{L: log.New(buf, "", 0)},
 	}
-	logInst, err := NewLogger(config, 255, 2)
+	logInst, err := NewLogger(config, 255, 2, true)
 	if err != nil {
 		t.Fatalf("failed to create logger: %v", err)
 	}
@@ -89,7 +105,7 @@ func TestLoggerLogLevelFiltering(t *testing.T) {
 		Level:         ptr(LogLevel.WARN),
 		ConsoleOut:    &config.ConsoleLogger{L: log.New(buf, "", 0)},
 	}
-	logInst, err := NewLogger(config, 255, 2)
+	logInst, err := NewLogger(config, 255, 2, true)
 	if err != nil {
 		t.Fatalf("failed to create logger: %v", err)
 	}
@@ -109,6 +125,165 @@ func TestLoggerLogLevelFiltering(t *testing.T) {
 	}
 }
 
+// Test TRACE filtering and that a registered level is named in both encodings.
+func TestLoggerCustomLevels(t *testing.T) {
+	if err := LogLevel.Register("AUDIT", LogLevel.INFO+1); err != nil {
+		t.Fatalf("failed to register level: %v", err)
+	}
+	tempDir := t.TempDir()
+	buf := new(bytes.Buffer)
+	cfg := &config.Config{
+		DirectoryPath: ptr(tempDir),
+		Level:         ptr(LogLevel.DEBUG),
+		FileEncoding:  ptr(format.JSON),
+		ConsoleOut:    &config.ConsoleLogger{L: log.New(buf, "", 0)},
+	}
+	logInst, err := NewLogger(cfg, 255, 2, true)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	defer logInst.Shutdown(time.Second)
+
+	logInst.Trace("trace message") // should be filtered out
+	logInst.UpdateConfig(config.Config{Level: ptr(LogLevel.TRACE)})
+	logInst.Tracef("trace %d", 2)
+	logInst.Log(LogLevel.INFO+1, "audit message")
+	logInst.Log(LogLevel.NONE, "threshold message") // not a severity, ignored
+	logInst.Logf(LogLevel.ALL, "threshold %s", "message")
+	time.Sleep(50 * time.Millisecond) // Allow the run loop to pick up the message
+	logInst.SyncFlush(time.Second)
+
+	console := buf.String()
+	if strings.Contains(console, "trace message") || !strings.Contains(console, "TRACE] [logger_test.go") {
+		t.Errorf("expected only the second trace message on the console, got %q", console)
+	}
+	if strings.Contains(console, "threshold message") {
+		t.Errorf("expected messages logged at a threshold to be ignored, got %q", console)
+	}
+	if !strings.Contains(console, "AUDIT] audit message") {
+		t.Errorf("expected console to name the registered level, got %q", console)
+	}
+	data, err := os.ReadFile(filepath.Join(tempDir, "latest.log"))
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	if !strings.Contains(string(data), `"level":"AUDIT","message":"audit message"`) {
+		t.Errorf("expected file to name the registered level, got %q", string(data))
+	}
+}
+
+// Test VModule overrides by logger name and caller file, including runtime updates and slog.
+func TestLoggerVModule(t *testing.T) {
+	buf := new(bytes.Buffer)
+	vmodule, err := config.ParseVModule("db=DEBUG,http.*=ERROR")
+	if err != nil {
+		t.Fatalf("failed to parse vmodule: %v", err)
+	}
+	cfg := &config.Config{
+		DirectoryPath: ptr(""),
+		Level:         ptr(LogLevel.INFO),
+		VModule:       &vmodule,
+		ConsoleOut:    &config.ConsoleLogger{L: log.New(buf, "", 0)},
+	}
+	logInst, err := NewLogger(cfg, 255, 2, true)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	defer logInst.Shutdown(time.Second)
+
+	db, http := logInst.Named("db"), logInst.Named("http").Named("server")
+	if !db.Enabled(LogLevel.DEBUG) || logInst.Enabled(LogLevel.DEBUG) || http.Enabled(LogLevel.WARN) {
+		t.Errorf("expected Enabled to follow the overrides matching the logger name")
+	}
+	for i := 0; i < 2; i++ { // the second pass hits the call site cache
+		db.Debug("db debug")
+		logInst.Debug("root debug") // filtered by the configured level
+		http.Warn("http warn")      // filtered by the override
+		http.Error("http error")
+		logInst.Info("root info")
+	}
+	// File overrides match the caller, this test file.
+	v2, _ := config.ParseVModule("logger/*=WARN")
+	logInst.UpdateConfig(config.Config{VModule: &v2})
+	db.Debug("db debug after update")
+	logInst.Info("root info after update")
+	logInst.Warn("root warn after update")
+	slog.New(logInst.SlogHandler()).Info("slog info after update")
+	time.Sleep(50 * time.Millisecond) // Allow the run loop to pick up the message
+	logInst.SyncFlush(time.Second)
+
+	output := buf.String()
+	for msg, want := range map[string]int{
+		"db debug": 2, "root debug": 0, "http warn": 0, "http error": 2, "root info": 2,
+		"db debug after update": 0, "root info after update": 0, "root warn after update": 1, "slog info after update": 0,
+	} {
+		if got := strings.Count(output, msg+"\n"); got != want {
+			t.Errorf("expected %q logged %d times, got %d in %q", msg, want, got, output)
+		}
+	}
+}
+
+// Test that concurrent level updates leave the copy read when logging equal to the applied config.
+func TestLoggerConcurrentUpdateConfig(t *testing.T) {
+	logInst, err := NewLogger(&config.Config{DirectoryPath: ptr(""), ConsoleWriter: io.Discard}, 255, 2, true)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	defer logInst.Shutdown(time.Second)
+
+	var wg sync.WaitGroup
+	for _, lvl := range []LogLevel.LogLevel{LogLevel.DEBUG, LogLevel.WARN, LogLevel.ERROR, LogLevel.TRACE} {
+		wg.Add(1)
+		go func(lvl LogLevel.LogLevel) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				logInst.UpdateConfig(config.Config{Level: ptr(lvl)})
+			}
+		}(lvl)
+	}
+	wg.Wait()
+	if got, applied := LogLevel.LogLevel(logInst.level.Load()), *logInst.GetConfigCopy().Level; got != applied {
+		t.Errorf("expected the level copy %s to match the applied level %s", got, applied)
+	}
+}
+
+// Test that the text layout applies to the file and console, and can be changed at runtime.
+func TestLoggerTextLayout(t *testing.T) {
+	tempDir := t.TempDir()
+	buf := new(bytes.Buffer)
+	cfg := &config.Config{
+		DirectoryPath: ptr(tempDir),
+		Level:         ptr(LogLevel.INFO),
+		ConsoleOut:    &config.ConsoleLogger{L: log.New(buf, "", 0)},
+	}
+	logInst, err := NewLogger(cfg, 255, 2, true)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	defer logInst.Shutdown(time.Second)
+
+	logInst.Info("default layout")
+	time.Sleep(50 * time.Millisecond) // Allow the run loop to pick up the message before the new layout
+	layout, err := format.NewLayout(format.LayoutOptions{Template: "{level} {message}{fields}", LevelCase: format.LowerCase})
+	if err != nil {
+		t.Fatalf("failed to compile layout: %v", err)
+	}
+	logInst.UpdateConfig(config.Config{TextLayout: &layout})
+	logInst.Info("custom layout", "k", "v")
+	time.Sleep(50 * time.Millisecond) // Allow the run loop to pick up the message
+	logInst.SyncFlush(time.Second)
+
+	data, err := os.ReadFile(filepath.Join(tempDir, "latest.log"))
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	for name, output := range map[string]string{"console": buf.String(), "file": string(data)} {
+		if !strings.Contains(output, "INFO]  default layout\n") || !strings.Contains(output, "\ninfo custom layout k=v\n") {
+			t.Errorf("expected %s output in the default then the custom layout, got %q", name, output)
+		}
+	}
+}
+
 // Test updating configuration dynamically.
 func TestLoggerConfigUpdate(t *testing.T) {
 	buf := new(bytes.Buffer)
@@ -117,7 +292,7 @@ func TestLoggerConfigUpdate(t *testing.T) {
 		Level:         ptr(LogLevel.INFO),
 		ConsoleOut:    &config.ConsoleLogger{L: log.New(buf, "", 0)},
 	}
-	logInst, err := NewLogger(cfg, 255, 2)
+	logInst, err := NewLogger(cfg, 255, 2, true)
 	if err != nil {
 		t.Fatalf("failed to create logger: %v", err)
 	}
@@ -143,3 +318,1228 @@ func TestLoggerConfigUpdate(t *testing.T) {
 		t.Errorf("expected no console output after disabling console logging, got %q", buf.String())
 	}
 }
+
+// Test that structured fields are rendered as key=value pairs.
+func TestLoggerFields(t *testing.T) {
+	buf := new(bytes.Buffer)
+	cfg := &config.Config{
+		DirectoryPath: ptr(""),
+		Level:         ptr(LogLevel.INFO),
+		ConsoleOut:    &config.ConsoleLogger{L: log.New(buf, "", 0)},
+	}
+	logInst, err := NewLogger(cfg, 255, 2, true)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	defer logInst.Shutdown(time.Second)
+
+	logInst.Info("login", "user", 42, record.String("ip", "10.0.0.1"), "note", "two words")
+	time.Sleep(50 * time.Millisecond) // Allow the run loop to pick up the message
+	logInst.SyncFlush(time.Second)
+
+	output := buf.String()
+	want := `login user=42 ip=10.0.0.1 note="two words"`
+	if !strings.Contains(output, want) {
+		t.Errorf("expected console output to contain %q, got %q", want, output)
+	}
+}
+
+// Test that the JSON encoding writes one object per line to the log file.
+func TestLoggerFileJSON(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		DirectoryPath: ptr(tempDir),
+		Level:         ptr(LogLevel.INFO),
+		FileEncoding:  ptr(format.JSON),
+	}
+	logInst, err := NewLogger(cfg, 255, 2, true)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	defer logInst.Shutdown(time.Second)
+
+	logInst.Info("first\nmessage", "user", 42)
+	logInst.Warn("second message")
+	time.Sleep(50 * time.Millisecond) // Allow the run loop to pick up the message
+	logInst.SyncFlush(time.Second)
+
+	data, err := os.ReadFile(filepath.Join(tempDir, "latest.log"))
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %q", len(lines), string(data))
+	}
+	var first struct {
+		Level   string         `json:"level"`
+		Message string         `json:"message"`
+		Fields  map[string]any `json:"fields"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("failed to decode line %q: %v", lines[0], err)
+	}
+	if first.Level != "INFO" || first.Message != "first\nmessage" || first.Fields["user"] != float64(42) {
+		t.Errorf("unexpected record: %+v", first)
+	}
+}
+
+// Test the slog handler's level mapping, Enabled, attrs and groups.
+func TestLoggerSlogHandler(t *testing.T) {
+	buf := new(bytes.Buffer)
+	cfg := &config.Config{
+		DirectoryPath: ptr(""),
+		Level:         ptr(LogLevel.INFO),
+		ConsoleOut:    &config.ConsoleLogger{L: log.New(buf, "", 0)},
+	}
+	logInst, err := NewLogger(cfg, 255, 2, true)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	defer logInst.Shutdown(time.Second)
+
+	h := logInst.SlogHandler()
+	if h.Enabled(context.Background(), slog.LevelDebug) {
+		t.Errorf("debug should be disabled at INFO level")
+	}
+	if !h.Enabled(context.Background(), slog.LevelWarn) {
+		t.Errorf("warn should be enabled at INFO level")
+	}
+
+	sl := slog.New(h).With("service", "api").WithGroup("req")
+	sl.Debug("filtered message")
+	sl.Warn("slow request", "method", "GET", slog.Group("user", "id", 7), slog.Group("empty"))
+	time.Sleep(50 * time.Millisecond) // Allow the run loop to pick up the message
+	logInst.SyncFlush(time.Second)
+
+	output := buf.String()
+	if strings.Contains(output, "filtered message") {
+		t.Errorf("debug message should be filtered out at INFO level")
+	}
+	want := "slow request service=api req.method=GET req.user.id=7\n"
+	if !strings.Contains(output, want) || strings.Contains(output, "empty") {
+		t.Errorf("expected console output to contain %q, got %q", want, output)
+	}
+
+	// Level changes are visible to Enabled.
+	logInst.UpdateConfig(config.Config{Level: ptr(LogLevel.DEBUG)})
+	if !h.Enabled(context.Background(), slog.LevelDebug) {
+		t.Errorf("debug should be enabled after raising the level to DEBUG")
+	}
+}
+
+// Test that child loggers stamp their name and bound fields without affecting the parent.
+func TestLoggerChildren(t *testing.T) {
+	buf := new(bytes.Buffer)
+	cfg := &config.Config{
+		DirectoryPath: ptr(""),
+		Level:         ptr(LogLevel.INFO),
+		ConsoleOut:    &config.ConsoleLogger{L: log.New(buf, "", 0)},
+	}
+	logInst, err := NewLogger(cfg, 255, 2, true)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	defer logInst.Shutdown(time.Second)
+
+	db := logInst.Named("db").With("conn", 1)
+	pool := db.Named("pool").With("size", 4)
+	db.Info("db message", "query", "select")
+	pool.Info("pool message")
+	logInst.Info("root message")
+	time.Sleep(50 * time.Millisecond) // Allow the run loop to pick up the message
+	logInst.SyncFlush(time.Second)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %q", buf.String())
+	}
+	expected := []string{"[db] db message conn=1 query=select", "[db.pool] pool message conn=1 size=4", "root message"}
+	for i, want := range expected {
+		if !strings.HasSuffix(lines[i], want) {
+			t.Errorf("expected line %d to end with %q, got %q", i, want, lines[i])
+		}
+	}
+	if strings.Contains(lines[2], "[db") || strings.Contains(lines[2], "conn=") {
+		t.Errorf("expected root logger to be unaffected by children, got %q", lines[2])
+	}
+}
+
+// Test that Enabled follows the configured level, including updates.
+func TestLoggerEnabled(t *testing.T) {
+	cfg := &config.Config{
+		DirectoryPath: ptr(""),
+		Level:         ptr(LogLevel.INFO),
+	}
+	logInst, err := NewLogger(cfg, 255, 2, true)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	defer logInst.Shutdown(time.Second)
+
+	if logInst.Enabled(LogLevel.DEBUG) || !logInst.Enabled(LogLevel.INFO) || !logInst.Enabled(LogLevel.ERROR) {
+		t.Errorf("unexpected Enabled results at INFO level")
+	}
+	logInst.UpdateConfig(config.Config{Level: ptr(LogLevel.NONE)})
+	if logInst.Enabled(LogLevel.ERROR) {
+		t.Errorf("expected nothing to be enabled at NONE level")
+	}
+}
+
+// Benchmark the cost of a message filtered out by the level. It should not format or enqueue anything.
+func BenchmarkLoggerFilteredDebugf(b *testing.B) {
+	cfg := &config.Config{
+		DirectoryPath: ptr(""),
+		Level:         ptr(LogLevel.INFO),
+	}
+	logInst, err := NewLogger(cfg, 255, 2, true)
+	if err != nil {
+		b.Fatalf("failed to create logger: %v", err)
+	}
+	defer logInst.Shutdown(time.Second)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		logInst.Debugf("filtered %d %s", i, "message")
+	}
+}
+
+// Benchmark a message filtered by a VModule file override, after the first call cached its call site.
+func BenchmarkLoggerVModuleFilteredDebugf(b *testing.B) {
+	vmodule := config.VModule{{Pattern: "db", Level: LogLevel.DEBUG}, {Pattern: "logger/*", Level: LogLevel.INFO}}
+	cfg := &config.Config{
+		DirectoryPath: ptr(""),
+		Level:         ptr(LogLevel.TRACE),
+		VModule:       &vmodule,
+	}
+	logInst, err := NewLogger(cfg, 255, 2, true)
+	if err != nil {
+		b.Fatalf("failed to create logger: %v", err)
+	}
+	defer logInst.Shutdown(time.Second)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		logInst.Debugf("filtered %d %s", i, "message")
+	}
+}
+
+// Benchmark a message filtered by the configured level while a VModule override lets another
+// logger's debug messages through, which needs no caller lookup.
+func BenchmarkLoggerNameVModuleFilteredDebugf(b *testing.B) {
+	vmodule := config.VModule{{Pattern: "db", Level: LogLevel.DEBUG}}
+	cfg := &config.Config{
+		DirectoryPath: ptr(""),
+		Level:         ptr(LogLevel.INFO),
+		VModule:       &vmodule,
+	}
+	logInst, err := NewLogger(cfg, 255, 2, true)
+	if err != nil {
+		b.Fatalf("failed to create logger: %v", err)
+	}
+	defer logInst.Shutdown(time.Second)
+
+	b.ReportAllocs()
+	http := logInst.Named("http")
+	for i := 0; i < b.N; i++ {
+		http.Debugf("filtered %d %s", i, "message")
+	}
+}
+
+// testSink records what the logger does with it.
+type testSink struct {
+	mu        sync.Mutex
+	messages  []string
+	locations []string
+	flushes   int
+	closed    bool
+}
+
+func (s *testSink) Write(r *record.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, r.Message)
+	s.locations = append(s.locations, r.Location)
+	return nil
+}
+
+func (s *testSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flushes++
+	return nil
+}
+
+func (s *testSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func (s *testSink) snapshot() ([]string, int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.messages...), s.flushes, s.closed
+}
+
+// Test registering additional sinks at creation and through UpdateConfig.
+func TestLoggerSinks(t *testing.T) {
+	first, second := &testSink{}, &testSink{}
+	buf := new(bytes.Buffer)
+	cfg := &config.Config{
+		DirectoryPath: ptr(""),
+		Level:         ptr(LogLevel.INFO),
+		ConsoleWriter: io.Discard,
+		Sinks:         []sink.Sink{first, sink.NewWriter(buf, format.JSON)},
+	}
+	logInst, err := NewLogger(cfg, 255, 2, true)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+
+	logInst.Info("one")
+	time.Sleep(50 * time.Millisecond) // Allow the run loop to pick up the message
+	logInst.SyncFlush(time.Second)
+	if msgs, flushes, _ := first.snapshot(); len(msgs) != 1 || msgs[0] != "one" || flushes == 0 {
+		t.Errorf("expected first sink to get one message and a flush, got %v and %d flushes", msgs, flushes)
+	}
+	if !strings.Contains(buf.String(), `"message":"one"`) {
+		t.Errorf("expected writer sink to contain the JSON record, got %q", buf.String())
+	}
+
+	// Replace the sinks, the removed ones get closed.
+	logInst.UpdateConfig(config.Config{Sinks: []sink.Sink{second}})
+	logInst.Info("two")
+	time.Sleep(50 * time.Millisecond) // Allow the run loop to pick up the message
+	logInst.SyncFlush(time.Second)
+	if msgs, _, closed := first.snapshot(); len(msgs) != 1 || !closed {
+		t.Errorf("expected removed sink to be closed and get no more messages, got %v, closed=%v", msgs, closed)
+	}
+	if msgs, _, _ := second.snapshot(); len(msgs) != 1 || msgs[0] != "two" {
+		t.Errorf("expected second sink to get the new message, got %v", msgs)
+	}
+
+	logInst.Shutdown(time.Second)
+	if _, _, closed := second.snapshot(); !closed {
+		t.Errorf("expected sinks to be closed on shutdown")
+	}
+}
+
+// Test that the file and console each apply their own level and encoding.
+func TestLoggerPerSinkLevelAndEncoding(t *testing.T) {
+	tempDir := t.TempDir()
+	buf := new(bytes.Buffer)
+	cfg := &config.Config{
+		DirectoryPath: ptr(tempDir),
+		Level:         ptr(LogLevel.DEBUG),
+		FileLevel:     ptr(LogLevel.WARN),
+		FileEncoding:  ptr(format.JSON),
+		ConsoleOut:    &config.ConsoleLogger{L: log.New(buf, "", 0)},
+	}
+	logInst, err := NewLogger(cfg, 255, 2, true)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	defer logInst.Shutdown(time.Second)
+
+	logInst.Debug("debug message")
+	logInst.Warn("warn message")
+	time.Sleep(50 * time.Millisecond) // Allow the run loop to pick up the message
+	logInst.SyncFlush(time.Second)
+
+	console := buf.String()
+	if !strings.Contains(console, "debug message") || !strings.Contains(console, "WARN]  warn message") {
+		t.Errorf("expected console to contain both messages as text, got %q", console)
+	}
+	data, err := os.ReadFile(filepath.Join(tempDir, "latest.log"))
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	if strings.Contains(string(data), "debug message") || !strings.Contains(string(data), `"message":"warn message"`) {
+		t.Errorf("expected file to contain only the warn message as JSON, got %q", string(data))
+	}
+
+	// Custom sinks can be given their own level too.
+	custom := &testSink{}
+	logInst.UpdateConfig(config.Config{Sinks: []sink.Sink{sink.WithLevel(custom, LogLevel.ERROR)}})
+	logInst.Warn("not for custom")
+	logInst.Error("for custom")
+	time.Sleep(50 * time.Millisecond) // Allow the run loop to pick up the message
+	logInst.SyncFlush(time.Second)
+	if msgs, _, _ := custom.snapshot(); len(msgs) != 1 || msgs[0] != "for custom" {
+		t.Errorf("expected custom sink to only get the error message, got %v", msgs)
+	}
+}
+
+// Test that the rotation schedule rotates latest.log at period boundaries, named for the period it covers.
+func TestLoggerScheduledRotation(t *testing.T) {
+	tempDir := t.TempDir()
+	schedule := config.Schedule{Interval: time.Second, UTC: true}
+	cfg := &config.Config{
+		DirectoryPath:    ptr(tempDir),
+		Level:            ptr(LogLevel.INFO),
+		RotationSchedule: ptr(schedule),
+	}
+	logInst, err := NewLogger(cfg, 255, 2, true)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	defer logInst.Shutdown(time.Second)
+
+	// Start right after a boundary so both messages land in the same period.
+	time.Sleep(time.Until(schedule.Next(time.Now())) + 20*time.Millisecond)
+	period := schedule.Start(time.Now())
+	logInst.Info("first period")
+	time.Sleep(time.Until(schedule.Next(period)) + 100*time.Millisecond)
+	logInst.Info("second period")
+	time.Sleep(50 * time.Millisecond) // Allow the run loop to pick up the message
+	logInst.SyncFlush(time.Second)
+
+	rotated := filepath.Join(tempDir, period.Format("2006-01-02_15-04-05")+".log")
+	data, err := os.ReadFile(rotated)
+	if err != nil {
+		t.Fatalf("expected rotated file %s: %v", rotated, err)
+	}
+	if !strings.Contains(string(data), "first period") || strings.Contains(string(data), "second period") {
+		t.Errorf("expected rotated file to contain only the first message, got %q", string(data))
+	}
+	data, err = os.ReadFile(filepath.Join(tempDir, "latest.log"))
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	if strings.Contains(string(data), "first period") || !strings.Contains(string(data), "second period") {
+		t.Errorf("expected latest.log to contain only the second message, got %q", string(data))
+	}
+}
+
+// Test that retention deletes the oldest rotated files at startup and leaves other files alone.
+func TestLoggerRetention(t *testing.T) {
+	tempDir := t.TempDir()
+	now := time.Now()
+	write := func(name string, size int, age time.Duration) {
+		path := filepath.Join(tempDir, name)
+		if err := os.WriteFile(path, bytes.Repeat([]byte("x"), size), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatalf("failed to set times on %s: %v", name, err)
+		}
+	}
+	write("2025-01-01_00-00-00.log", 10, 40*24*time.Hour) // too old
+	write("2025-02-01_00-00-00.log", 10, 5*time.Hour)     // over the file count
+	write("2025-02-02_00-00-00.log", 10, 4*time.Hour)
+	write("2025-02-03_00-00-00_abcdefghijkl.log", 10, 3*time.Hour)
+	write("2025-02-04_00-00-00.log", 10, 2*time.Hour)
+	write("notes.log", 10, 50*24*time.Hour) // not ours
+
+	cfg := &config.Config{
+		DirectoryPath: ptr(tempDir),
+		Level:         ptr(LogLevel.INFO),
+		Retention:     ptr(config.Retention{MaxFiles: 3, MaxAge: 30 * 24 * time.Hour}),
+	}
+	logInst, err := NewLogger(cfg, 255, 2, true)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	defer logInst.Shutdown(time.Second)
+
+	expectFiles := func(expected ...string) {
+		t.Helper()
+		entries, err := os.ReadDir(tempDir)
+		if err != nil {
+			t.Fatalf("failed to read dir: %v", err)
+		}
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		if strings.Join(names, ",") != strings.Join(expected, ",") {
+			t.Errorf("expected files %v, got %v", expected, names)
+		}
+	}
+	expectFiles("2025-02-02_00-00-00.log", "2025-02-03_00-00-00_abcdefghijkl.log", "2025-02-04_00-00-00.log", "notes.log")
+
+	// Tightening the policy at runtime applies it right away.
+	logInst.UpdateConfig(config.Config{Retention: ptr(config.Retention{MaxTotalSizeBytes: 15})})
+	logInst.SyncFlush(time.Second)
+	expectFiles("2025-02-04_00-00-00.log", "notes.log")
+}
+
+// Test that rotated files are compressed in the background and the original removed.
+func TestLoggerCompression(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		DirectoryPath:    ptr(tempDir),
+		Level:            ptr(LogLevel.INFO),
+		MaxFileSizeBytes: ptr(10),
+		Compress:         ptr(true),
+	}
+	logInst, err := NewLogger(cfg, 255, 2, true)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	defer logInst.Shutdown(time.Second)
+
+	logInst.Info("first message, over the size limit")
+	time.Sleep(50 * time.Millisecond) // Allow the run loop to pick up the message
+	logInst.SyncFlush(time.Second)
+	logInst.Info("second message, triggers rotation")
+	time.Sleep(50 * time.Millisecond) // Allow the run loop to pick up the message
+	logInst.SyncFlush(time.Second)
+
+	// Wait for the background compression to finish.
+	var matches []string
+	for i := 0; i < 100 && len(matches) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		matches, _ = filepath.Glob(filepath.Join(tempDir, "*.log.gz"))
+	}
+	if len(matches) != 1 {
+		t.Fatalf("expected one compressed file, got %v", matches)
+	}
+	if plain, _ := filepath.Glob(filepath.Join(tempDir, "*_*.log")); len(plain) != 0 {
+		t.Errorf("expected the uncompressed original to be removed, got %v", plain)
+	}
+
+	f, err := os.Open(matches[0])
+	if err != nil {
+		t.Fatalf("failed to open compressed file: %v", err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("failed to read gzip header: %v", err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("failed to decompress: %v", err)
+	}
+	if !strings.Contains(string(data), "first message") {
+		t.Errorf("expected compressed file to contain the first message, got %q", string(data))
+	}
+	if !(config.Naming{}).IsRotated(filepath.Base(matches[0])) {
+		t.Errorf("expected %s to match the rotated naming pattern", matches[0])
+	}
+}
+
+// Test that Shutdown waits for background compression, and that a new logger cleans up after an
+// interrupted one.
+func TestLoggerCompressionRecovery(t *testing.T) {
+	tempDir := t.TempDir()
+	// A rotated file whose compression was interrupted, and one never compressed at all.
+	for name, data := range map[string]string{
+		"2025-02-01_00-00-00.log":        "interrupted\n",
+		"2025-02-01_00-00-00.log.gz.tmp": "partial",
+		"2025-02-02_00-00-00.log":        "uncompressed\n",
+		"notes.log.gz.tmp":               "not ours",
+	} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(data), 0644); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+	cfg := &config.Config{
+		DirectoryPath:    ptr(tempDir),
+		Level:            ptr(LogLevel.INFO),
+		MaxFileSizeBytes: ptr(10),
+		Compress:         ptr(true),
+	}
+	logInst, err := NewLogger(cfg, 255, 2, true)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	logInst.Info("first message, over the size limit")
+	time.Sleep(50 * time.Millisecond) // Allow the run loop to pick up the message
+	logInst.SyncFlush(time.Second)
+	logInst.Info("second message, triggers rotation")
+	if err := logInst.Shutdown(0); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+
+	// Everything was compressed by the time Shutdown returned.
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatalf("failed to read log directory: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	compressed := 0
+	for _, name := range names {
+		switch {
+		case name == "latest.log" || name == "notes.log.gz.tmp":
+		case strings.HasSuffix(name, ".log.gz"):
+			compressed++
+		default:
+			t.Errorf("unexpected file %s left in %v", name, names)
+		}
+	}
+	if compressed != 3 {
+		t.Errorf("expected 3 compressed files, got %v", names)
+	}
+}
+
+// Test that a custom naming scheme is used for the active and rotated files, with sequence suffixes.
+func TestLoggerFileNaming(t *testing.T) {
+	tempDir := t.TempDir()
+	naming := config.Naming{Prefix: "api-", TimeLayout: "2006-01-02", UTC: true, Sequence: true, Extension: ".txt"}
+	cfg := &config.Config{
+		DirectoryPath:    ptr(tempDir),
+		Level:            ptr(LogLevel.INFO),
+		MaxFileSizeBytes: ptr(10),
+		FileNaming:       ptr(naming),
+	}
+	logInst, err := NewLogger(cfg, 255, 2, true)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	defer logInst.Shutdown(time.Second)
+
+	for _, msg := range []string{"first message", "second message", "third message"} {
+		logInst.Info(msg)
+		time.Sleep(50 * time.Millisecond) // Allow the run loop to pick up the message
+		logInst.SyncFlush(time.Second)
+	}
+
+	day := time.Now().UTC().Format("2006-01-02")
+	for name, msg := range map[string]string{
+		"api-" + day + ".txt":   "first message",
+		"api-" + day + "_1.txt": "second message",
+		"api-latest.txt":        "third message",
+	} {
+		data, err := os.ReadFile(filepath.Join(tempDir, name))
+		if err != nil {
+			t.Fatalf("expected file %s: %v", name, err)
+		}
+		if !strings.Contains(string(data), msg) {
+			t.Errorf("expected %s to contain %q, got %q", name, msg, string(data))
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "latest.log")); !os.IsNotExist(err) {
+		t.Errorf("expected no latest.log, got err %v", err)
+	}
+}
+
+// Test that the file is held open between flushes and reopened under the new name after rotation.
+func TestLoggerFileHeldOpen(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		DirectoryPath:    ptr(tempDir),
+		Level:            ptr(LogLevel.INFO),
+		MaxFileSizeBytes: ptr(100),
+	}
+	logInst, err := NewLogger(cfg, 255, 2, true)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	defer logInst.Shutdown(time.Second)
+
+	logInst.Info("first message")
+	time.Sleep(50 * time.Millisecond) // Allow the run loop to pick up the message
+	logInst.SyncFlush(time.Second)
+	first := logInst.file.file
+	logInst.Info("second message")
+	time.Sleep(50 * time.Millisecond) // Allow the run loop to pick up the message
+	logInst.SyncFlush(time.Second)
+	if first == nil || logInst.file.file != first {
+		t.Errorf("expected the file to stay open between flushes")
+	}
+
+	// A message that doesn't fit rotates the file and the next write opens a new one.
+	logInst.Info(strings.Repeat("x", 100))
+	time.Sleep(50 * time.Millisecond) // Allow the run loop to pick up the message
+	logInst.SyncFlush(time.Second)
+	logInst.Info("after rotation")
+	time.Sleep(50 * time.Millisecond) // Allow the run loop to pick up the message
+	logInst.SyncFlush(time.Second)
+	data, err := os.ReadFile(filepath.Join(tempDir, "latest.log"))
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	if string(data) == "" || strings.Contains(string(data), "first message") || !strings.Contains(string(data), "after rotation") {
+		t.Errorf("expected latest.log to only contain the message after rotation, got %q", string(data))
+	}
+	if info, err := os.Stat(filepath.Join(tempDir, "latest.log")); err != nil || info.Size() != logInst.file.size {
+		t.Errorf("expected tracked size %d to match the file, got %v, %v", logInst.file.size, info, err)
+	}
+}
+
+// Benchmark flushing records to the log file, holding the file open versus reopening it on every
+// flush as the file writer used to.
+func BenchmarkFileSinkFlush(b *testing.B) {
+	for _, bm := range []struct {
+		name   string
+		reopen bool
+	}{{"HeldOpen", false}, {"ReopenEachFlush", true}} {
+		b.Run(bm.name, func(b *testing.B) {
+			l := &Logger{core: &core{config: &config.Config{DirectoryPath: ptr(b.TempDir())}}}
+			l.config.ApplyDefaults()
+			l.file = &fileSink{l: l}
+			defer l.file.Close()
+			r := &record.Record{Time: time.Now(), Level: LogLevel.INFO, Message: "benchmark message with a typical length"}
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				l.file.Write(r)
+				l.file.Flush()
+				if bm.reopen {
+					l.file.closeFile()
+				}
+			}
+		})
+	}
+}
+
+// Test that a large buffer is split on record boundaries across as many files as needed, with every
+// file within the size limit except one holding a single oversized record.
+func TestLoggerExactSizeRotation(t *testing.T) {
+	tempDir := t.TempDir()
+	const limit = 120
+	cfg := &config.Config{
+		DirectoryPath:      ptr(tempDir),
+		Level:              ptr(LogLevel.INFO),
+		MaxFileSizeBytes:   ptr(limit),
+		MaxBufferSizeBytes: ptr(1 << 20),
+		FileNaming:         ptr(config.Naming{TimeLayout: "20060102150405", Sequence: true}),
+	}
+	logInst, err := NewLogger(cfg, 255, 2, true)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	defer logInst.Shutdown(time.Second)
+
+	var expected []string
+	for i := 0; i < 10; i++ {
+		msg := fmt.Sprintf("message %d", i)
+		if i == 5 {
+			msg = strings.Repeat("x", 2*limit)
+		}
+		expected = append(expected, msg)
+		logInst.Info(msg)
+	}
+	time.Sleep(50 * time.Millisecond) // Allow the run loop to pick up the messages
+	logInst.SyncFlush(time.Second)
+
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatalf("failed to read dir: %v", err)
+	}
+	var files []os.FileInfo
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			t.Fatalf("failed to stat %s: %v", e.Name(), err)
+		}
+		files = append(files, info)
+	}
+	// Rotated files may share a timestamp, so order them by time then sequence number with latest.log last.
+	key := func(name string) (string, int) {
+		if name == "latest.log" {
+			return "~", 0
+		}
+		stem, suffix, _ := strings.Cut(strings.TrimSuffix(name, ".log"), "_")
+		n, _ := strconv.Atoi(suffix)
+		return stem, n
+	}
+	sort.Slice(files, func(i, j int) bool {
+		ti, ni := key(files[i].Name())
+		tj, nj := key(files[j].Name())
+		return ti < tj || (ti == tj && ni < nj)
+	})
+
+	var got []string
+	for _, f := range files {
+		data, err := os.ReadFile(filepath.Join(tempDir, f.Name()))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", f.Name(), err)
+		}
+		lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		if f.Size() > limit && len(lines) != 1 {
+			t.Errorf("expected %s to be within %d bytes or hold a single record, got %d bytes", f.Name(), limit, f.Size())
+		}
+		for _, line := range lines {
+			got = append(got, line[strings.Index(line, "] ")+2:])
+		}
+	}
+	if len(files) < 4 {
+		t.Errorf("expected the buffer to be split across several files, got %d", len(files))
+	}
+	for i := range expected {
+		if i >= len(got) || strings.TrimSpace(got[i]) != expected[i] {
+			t.Fatalf("expected records in order %q, got %q", expected, got)
+		}
+	}
+}
+
+// Test that external rotation disables size rotation, and that Reopen and SIGHUP start a new file
+// after the old one was moved away.
+func TestLoggerExternalRotation(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		DirectoryPath:    ptr(tempDir),
+		Level:            ptr(LogLevel.INFO),
+		MaxFileSizeBytes: ptr(10),
+		ExternalRotation: ptr(true),
+	}
+	logInst, err := NewLogger(cfg, 255, 2, true)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	defer logInst.Shutdown(time.Second)
+	latest := filepath.Join(tempDir, "latest.log")
+
+	logMoveAndReopen := func(msg, movedName string, reopen func()) {
+		t.Helper()
+		logInst.Info(msg + " one")
+		logInst.Info(msg + " two")
+		time.Sleep(50 * time.Millisecond) // Allow the run loop to pick up the messages
+		logInst.SyncFlush(time.Second)
+		if err := os.Rename(latest, filepath.Join(tempDir, movedName)); err != nil {
+			t.Fatalf("failed to move log file: %v", err)
+		}
+		reopen()
+		logInst.SyncFlush(time.Second)
+	}
+	logMoveAndReopen("before reopen", "latest.log.1", logInst.Reopen)
+	if runtime.GOOS != "windows" {
+		logMoveAndReopen("before hup", "latest.log.2", func() {
+			p, _ := os.FindProcess(os.Getpid())
+			if err := p.Signal(syscall.SIGHUP); err != nil {
+				t.Fatalf("failed to send SIGHUP: %v", err)
+			}
+			time.Sleep(50 * time.Millisecond) // Allow the run loop to pick up the signal
+		})
+	}
+	logInst.Info("after reopen")
+	time.Sleep(50 * time.Millisecond) // Allow the run loop to pick up the message
+	logInst.SyncFlush(time.Second)
+
+	entries, _ := os.ReadDir(tempDir)
+	expectedFiles := utils.Ternary(runtime.GOOS != "windows", 3, 2)
+	if len(entries) != expectedFiles {
+		t.Errorf("expected blog not to rotate on its own, got %v", entries)
+	}
+	data, err := os.ReadFile(filepath.Join(tempDir, "latest.log.1"))
+	if err != nil || !strings.Contains(string(data), "before reopen one") || !strings.Contains(string(data), "before reopen two") {
+		t.Errorf("expected the moved file to keep both messages, got %q, %v", string(data), err)
+	}
+	data, err = os.ReadFile(latest)
+	if err != nil || strings.Contains(string(data), "before") || !strings.Contains(string(data), "after reopen") {
+		t.Errorf("expected a new latest.log with only the last message, got %q, %v", string(data), err)
+	}
+}
+
+// gatedSink is a testSink whose writes block until the gate is closed, to back up the message channel.
+type gatedSink struct {
+	testSink
+	entered chan struct{}
+	gate    chan struct{}
+}
+
+func (s *gatedSink) Write(r *record.Record) error {
+	select {
+	case s.entered <- struct{}{}:
+	default:
+	}
+	<-s.gate
+	return s.testSink.Write(r)
+}
+
+// Test each overflow policy with a full message channel, and the "messages dropped" record once it drains.
+func TestLoggerOverflow(t *testing.T) {
+	tests := []struct {
+		name     string
+		level    LogLevel.LogLevel
+		overflow config.Overflow
+		log      func(l *Logger)
+		blocked  func(l *Logger) // logged from another goroutine as it is expected to wait for room
+		expected []string
+		dropped  uint64
+	}{
+		{
+			name:     "DropNewest",
+			overflow: config.Overflow{Policy: config.DropNewest},
+			log: func(l *Logger) {
+				for i := 1; i <= 5; i++ {
+					l.Infof("m%d", i)
+				}
+			},
+			expected: []string{"m0", "m1", "m2"},
+			dropped:  3,
+		},
+		{
+			name:     "DropOldest",
+			overflow: config.Overflow{Policy: config.DropOldest},
+			log: func(l *Logger) {
+				for i := 1; i <= 5; i++ {
+					l.Infof("m%d", i)
+				}
+			},
+			expected: []string{"m0", "m4", "m5"},
+			dropped:  3,
+		},
+		{
+			name:     "DropOldestKeepsFatal",
+			overflow: config.Overflow{Policy: config.DropOldest},
+			log: func(l *Logger) {
+				l.qM(LogLevel.FATAL, nil, nil, "f1") // stays at the head, its neighbour is dropped
+				l.Info("m2")
+				l.Info("m3")
+				l.Info("m4")
+			},
+			expected: []string{"m0", "f1", "m3", "m4"},
+			dropped:  1,
+		},
+		{
+			name:     "ReportAboveLevel",
+			level:    LogLevel.ERROR,
+			overflow: config.Overflow{Policy: config.DropNewest},
+			log: func(l *Logger) {
+				for i := 1; i <= 3; i++ {
+					l.Errorf("m%d", i)
+				}
+			},
+			expected: []string{"m0", "m1", "m2"},
+			dropped:  1,
+		},
+		{
+			name:     "DropBelow",
+			overflow: config.Overflow{Policy: config.DropBelow, Level: LogLevel.WARN},
+			log: func(l *Logger) {
+				l.Warn("w1")
+				l.Warn("w2")
+				l.Info("i3")
+			},
+			blocked:  func(l *Logger) { l.Warn("w4") },
+			expected: []string{"m0", "w1", "w2", "w4"},
+			dropped:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &gatedSink{entered: make(chan struct{}, 1), gate: make(chan struct{})}
+			cfg := &config.Config{
+				DirectoryPath: ptr(""),
+				Level:         ptr(tt.level),
+				Overflow:      ptr(tt.overflow),
+				ConsoleWriter: io.Discard,
+				Sinks:         []sink.Sink{s},
+			}
+			logInst, err := NewLogger(cfg, 2, 2, true)
+			if err != nil {
+				t.Fatalf("failed to create logger: %v", err)
+			}
+			defer logInst.Shutdown(time.Second)
+
+			logInst.Error("m0")
+			<-s.entered // the logger goroutine is now stuck in the sink with an empty channel
+			tt.log(logInst)
+			var wg sync.WaitGroup
+			if tt.blocked != nil {
+				wg.Add(1)
+				go func() { defer wg.Done(); tt.blocked(logInst) }()
+				time.Sleep(50 * time.Millisecond) // Let it block on the full channel
+			}
+			close(s.gate)
+			wg.Wait()
+			time.Sleep(50 * time.Millisecond) // Allow the run loop to drain the channel
+			logInst.SyncFlush(time.Second)
+
+			messages, _, _ := s.snapshot()
+			var got []string
+			reports := 0
+			for _, m := range messages {
+				if m == fmt.Sprintf("%d messages dropped", tt.dropped) {
+					reports++
+				} else {
+					got = append(got, m)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected messages %v, got %v", tt.expected, messages)
+			}
+			if reports != 1 {
+				t.Errorf("expected one report of %d dropped messages, got %v", tt.dropped, messages)
+			}
+			if n := logInst.Dropped(); n != tt.dropped {
+				t.Errorf("expected %d dropped messages, got %d", tt.dropped, n)
+			}
+		})
+	}
+}
+
+// Test that file logging resumes once the directory is usable again, logging the outage, and that
+// the console enabled by the fallback is disabled again.
+func TestLoggerRecovery(t *testing.T) {
+	tempDir := filepath.Join(t.TempDir(), "logs")
+	if err := os.Mkdir(tempDir, 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	cfg := &config.Config{
+		DirectoryPath: ptr(tempDir),
+		Level:         ptr(LogLevel.INFO),
+		Recovery:      ptr(config.Recovery{InitialDelay: 20 * time.Millisecond, MaxDelay: 40 * time.Millisecond}),
+		ConsoleWriter: io.Discard,
+	}
+	logInst, err := NewLogger(cfg, 255, 2, true)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	defer logInst.Shutdown(time.Second)
+
+	// Pull the directory out from under the logger, the next flush fails and falls back to the console.
+	if err := os.RemoveAll(tempDir); err != nil {
+		t.Fatalf("failed to remove dir: %v", err)
+	}
+	logInst.Reopen()
+	logInst.Info("during outage")
+	time.Sleep(50 * time.Millisecond) // Allow the run loop to pick up the message
+	logInst.SyncFlush(time.Second)
+	time.Sleep(100 * time.Millisecond) // a few failed retries
+
+	if err := os.Mkdir(tempDir, 0755); err != nil {
+		t.Fatalf("failed to recreate dir: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond) // Allow a retry to succeed
+	logInst.Info("after outage")
+	time.Sleep(50 * time.Millisecond) // Allow the run loop to pick up the message
+	logInst.SyncFlush(time.Second)
+
+	got := logInst.GetConfigCopy()
+	if *got.DirectoryPath != tempDir || got.ConsoleOut.L != nil {
+		t.Errorf("expected file logging back in %s with the console disabled, got %q, %v", tempDir, *got.DirectoryPath, got.ConsoleOut.L)
+	}
+	data, err := os.ReadFile(filepath.Join(tempDir, "latest.log"))
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	content := string(data)
+	if !strings.Contains(content, "file logging resumed") || !strings.Contains(content, "outage=") || !strings.Contains(content, "after outage") {
+		t.Errorf("expected the outage record and the later message, got %q", content)
+	}
+	if strings.Contains(content, "during outage") {
+		t.Errorf("expected the message from during the outage to have gone to the console, got %q", content)
+	}
+}
+
+// Test that a directory that opens but keeps failing writes backs off, and that the resume record is
+// logged above the configured level.
+func TestLoggerRecoveryBackoff(t *testing.T) {
+	if _, err := os.Stat("/dev/full"); err != nil {
+		t.Skip("needs /dev/full")
+	}
+	tempDir := t.TempDir()
+	if err := os.Symlink("/dev/full", filepath.Join(tempDir, "latest.log")); err != nil {
+		t.Fatalf("failed to link latest.log: %v", err)
+	}
+	s := &testSink{}
+	cfg := &config.Config{
+		DirectoryPath:    ptr(tempDir),
+		Level:            ptr(LogLevel.ERROR),
+		ExternalRotation: ptr(true), // /dev/full has no size, never rotate it
+		Recovery:         ptr(config.Recovery{InitialDelay: 10 * time.Millisecond}),
+		ConsoleWriter:    io.Discard,
+		Sinks:            []sink.Sink{s},
+	}
+	logInst, err := NewLogger(cfg, 255, 2, true)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	defer logInst.Shutdown(time.Second)
+
+	logInst.Error("first")
+	deadline := time.Now().Add(300 * time.Millisecond)
+	for time.Now().Before(deadline) { // every resume fails on the next flush
+		logInst.SyncFlush(time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	messages, _, _ := s.snapshot()
+	resumed := 0
+	for _, m := range messages {
+		if m == "file logging resumed" {
+			resumed++
+		}
+	}
+	// 10, 20, 40, 80 and 160ms apart allows 5 within 300ms, without backoff it would be about 30.
+	if resumed == 0 || resumed > 6 {
+		t.Errorf("expected a few resumes backing off, got %d", resumed)
+	}
+}
+
+// failingSink fails every call.
+type failingSink struct{}
+
+func (failingSink) Write(*record.Record) error { return errors.New("write refused") }
+func (failingSink) Flush() error               { return nil }
+func (failingSink) Close() error               { return nil }
+
+// Test that internal failures reach the OnError hook wrapped in their typed errors.
+func TestLoggerOnError(t *testing.T) {
+	tempDir := t.TempDir()
+	var mu sync.Mutex
+	var errs []error
+	cfg := &config.Config{
+		DirectoryPath: ptr(tempDir),
+		Level:         ptr(LogLevel.INFO),
+		ConsoleOut:    &config.ConsoleLogger{L: log.New(io.Discard, "", 0)},
+		Sinks:         []sink.Sink{failingSink{}},
+		OnError: func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		},
+	}
+	logInst, err := NewLogger(cfg, 255, 2, true)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	defer logInst.Shutdown(time.Second)
+
+	logInst.Info("refused by the sink")
+	logInst.UpdateConfig(config.Config{DirectoryPath: ptr(filepath.Join(tempDir, "missing"))})
+	logInst.UpdateConfig(config.Config{DirectoryPath: ptr(tempDir), Sinks: []sink.Sink{}})
+	if err := os.Mkdir(filepath.Join(tempDir, "latest.log"), 0755); err != nil {
+		t.Fatalf("failed to block the log file: %v", err)
+	}
+	logInst.Info("unwritable")
+	time.Sleep(50 * time.Millisecond) // Allow the run loop to pick up the message
+	logInst.SyncFlush(time.Second)
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, target := range []error{ErrSink, ErrInvalidPath, ErrWrite} {
+		found := false
+		for _, err := range errs {
+			found = found || errors.Is(err, target)
+		}
+		if !found {
+			t.Errorf("expected an error wrapping %v, got %v", target, errs)
+		}
+	}
+}
+
+// Test that Shutdown handles every queued message, and reports the ones lost when it times out.
+func TestLoggerShutdownDrain(t *testing.T) {
+	s := &testSink{}
+	logInst, err := NewLogger(&config.Config{DirectoryPath: ptr(""), Level: ptr(LogLevel.INFO), ConsoleWriter: io.Discard, Sinks: []sink.Sink{s}}, 255, 2, true)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	for i := 0; i < 200; i++ {
+		logInst.Infof("message %d", i)
+	}
+	if err := logInst.Shutdown(time.Second); err != nil {
+		t.Errorf("expected a clean shutdown, got %v", err)
+	}
+	if messages, _, closed := s.snapshot(); len(messages) != 200 || !closed {
+		t.Errorf("expected all 200 messages before the sink was closed, got %d, closed %v", len(messages), closed)
+	}
+	if err := logInst.Start(); err == nil {
+		t.Errorf("expected Start to fail once the sinks are closed")
+	}
+
+	// With the goroutine stuck in a sink, the shutdown times out before it starts. The logger keeps
+	// running, and the queued messages are still written.
+	gs := &gatedSink{entered: make(chan struct{}, 1), gate: make(chan struct{})}
+	logInst, err = NewLogger(&config.Config{DirectoryPath: ptr(""), Level: ptr(LogLevel.INFO), ConsoleWriter: io.Discard, Sinks: []sink.Sink{gs}}, 255, 2, true)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	logInst.Info("stuck")
+	<-gs.entered
+	for i := 0; i < 5; i++ {
+		logInst.Infof("queued %d", i)
+	}
+	if err := logInst.Shutdown(50 * time.Millisecond); err == nil || !strings.Contains(err.Error(), "still running with 5 queued messages") {
+		t.Errorf("expected the logger to report it is still running, got %v", err)
+	}
+	close(gs.gate)
+	if err := logInst.Shutdown(0); err != nil {
+		t.Errorf("expected the retried shutdown to succeed, got %v", err)
+	}
+	if messages, _, closed := gs.snapshot(); len(messages) != 6 || !closed {
+		t.Errorf("expected the queued messages to be written after all, got %v, closed %v", messages, closed)
+	}
+}
+
+// stubExit replaces os.Exit and resets the exit state for the duration of the test, returning the
+// exit codes used.
+func stubExit(t *testing.T) *[]int {
+	codes := &[]int{}
+	osExit = func(code int) { *codes = append(*codes, code) }
+	t.Cleanup(func() {
+		osExit = os.Exit
+		exitOnce = sync.Once{}
+		exitHooks = nil
+	})
+	return codes
+}
+
+// Test that Fatal exits once its message is flushed, without waiting out the timeout, after running
+// the exit hooks, and only exits once.
+func TestLoggerFatal(t *testing.T) {
+	codes := stubExit(t)
+	var order []string
+	RegisterExitHook(func() { order = append(order, "hook") })
+	RegisterExitHook(func() { panic("hooks can't stop the exit") })
+	s := &testSink{}
+	logInst, err := NewLogger(&config.Config{DirectoryPath: ptr(""), Level: ptr(LogLevel.INFO), ConsoleWriter: io.Discard, Sinks: []sink.Sink{s}}, 255, 2, true)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	defer logInst.Shutdown(time.Second)
+
+	start := time.Now()
+	logInst.Fatal(3, 10*time.Second, "fatal message")
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected Fatal to exit once the message was flushed, took %s", elapsed)
+	}
+	messages, flushes, _ := s.snapshot()
+	if len(messages) != 1 || messages[0] != "fatal message" || flushes == 0 {
+		t.Errorf("expected the fatal message to be written and flushed, got %v with %d flushes", messages, flushes)
+	}
+	if strings.Join(order, ",") != "hook" {
+		t.Errorf("expected the exit hooks to run, got %v", order)
+	}
+
+	logInst.Fatal(4, time.Second, "second fatal message")
+	if len(*codes) != 1 || (*codes)[0] != 3 {
+		t.Errorf("expected a single exit with code 3, got %v", *codes)
+	}
+
+	// Both report the caller's location, not a line inside the logger.
+	logInst.Fatalf(5, time.Second, "formatted %s", "fatal message")
+	s.mu.Lock()
+	locations := append([]string(nil), s.locations...)
+	s.mu.Unlock()
+	if len(locations) != 3 {
+		t.Fatalf("expected three fatal messages, got locations %v", locations)
+	}
+	for _, loc := range locations {
+		if !strings.HasPrefix(loc, "logger_test.go:") {
+			t.Errorf("expected the location of the call in this file, got %v", locations)
+		}
+	}
+}
+
+// Test that Fatal gives up waiting after the timeout and still exits.
+func TestLoggerFatalTimeout(t *testing.T) {
+	codes := stubExit(t)
+	gs := &gatedSink{entered: make(chan struct{}, 1), gate: make(chan struct{})}
+	logInst, err := NewLogger(&config.Config{DirectoryPath: ptr(""), Level: ptr(LogLevel.INFO), ConsoleWriter: io.Discard, Sinks: []sink.Sink{gs}}, 255, 2, true)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	defer logInst.Shutdown(time.Second)
+	defer close(gs.gate)
+
+	start := time.Now()
+	logInst.Fatal(5, 50*time.Millisecond, "stuck fatal message")
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed > time.Second {
+		t.Errorf("expected Fatal to wait for the timeout, took %s", elapsed)
+	}
+	if len(*codes) != 1 || (*codes)[0] != 5 {
+		t.Errorf("expected a single exit with code 5, got %v", *codes)
+	}
+}
@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// rotatedFile is a rotated log file found in the log directory.
+type rotatedFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// listRotated returns the rotated files in the log directory, oldest first.
+func (s *fileSink) listRotated() ([]rotatedFile, error) {
+	entries, err := os.ReadDir(*s.l.config.DirectoryPath)
+	if err != nil {
+		return nil, err
+	}
+	var files []rotatedFile
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !s.l.config.FileNaming.IsRotated(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue // removed since ReadDir
+		}
+		files = append(files, rotatedFile{path: filepath.Join(*s.l.config.DirectoryPath, e.Name()), size: info.Size(), modTime: info.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	return files, nil
+}
+
+// enforceRetention deletes the oldest rotated files until the retention policy is met.
+// Only files matching the rotated naming pattern are ever deleted.
+func (s *fileSink) enforceRetention() error {
+	policy := *s.l.config.Retention
+	if !policy.Enabled() || *s.l.config.DirectoryPath == "" {
+		return nil
+	}
+	files, err := s.listRotated()
+	if err != nil {
+		return fmt.Errorf("%w: failed to list them: %w", ErrRetention, err)
+	}
+	// Work out the total size, including the active file
+	var total int64
+	if info, err := os.Stat(s.getLatestPath()); err == nil {
+		total = info.Size()
+	}
+	for _, f := range files {
+		total += f.size
+	}
+	// Delete from the oldest until every limit is met
+	cutoff := time.Now().Add(-policy.MaxAge)
+	remaining := len(files)
+	for _, f := range files {
+		tooMany := policy.MaxFiles > 0 && remaining > policy.MaxFiles
+		tooOld := policy.MaxAge > 0 && f.modTime.Before(cutoff)
+		tooBig := policy.MaxTotalSizeBytes > 0 && total > int64(policy.MaxTotalSizeBytes)
+		if !tooMany && !tooOld && !tooBig {
+			break
+		}
+		if s.isCompressing(f.path) {
+			continue // removed by a later pass, deleting it now would only resurrect it as a .gz
+		}
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("%w: %w", ErrRetention, err)
+		}
+		remaining--
+		total -= f.size
+	}
+	return nil
+}
+
+// applyRetention enforces the retention policy, reporting rather than returning any error
+// since a failed cleanup shouldn't stop logging.
+func (s *fileSink) applyRetention() {
+	if err := s.enforceRetention(); err != nil {
+		s.l.reportError(err)
+	}
+}
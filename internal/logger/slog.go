@@ -0,0 +1,128 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	LogLevel "github.com/Data-Corruption/blog/v3/internal/level"
+	"github.com/Data-Corruption/blog/v3/internal/record"
+)
+
+// slogHandler is a slog.Handler that feeds records into a Logger's message channel.
+// Groups are flattened into dotted field keys, e.g. WithGroup("req") then "method" becomes "req.method".
+type slogHandler struct {
+	l      *Logger
+	fields []record.Field // fields added via WithAttrs, already prefixed
+	prefix string         // current group prefix, e.g. "req."
+}
+
+// SlogHandler returns a slog.Handler that writes through this logger. Records below the logger's
+// current level are rejected by Enabled, so slog never builds them.
+func (l *Logger) SlogHandler() slog.Handler {
+	return &slogHandler{l: l, fields: l.fields}
+}
+
+// FromSlogLevel converts a slog.Level to a LogLevel. The values are shared, so levels in between the
+// named ones, e.g. slog.LevelInfo+2, keep their exact severity.
+func FromSlogLevel(lvl slog.Level) LogLevel.LogLevel {
+	return LogLevel.LogLevel(lvl)
+}
+
+func (h *slogHandler) Enabled(_ context.Context, lvl slog.Level) bool {
+	return h.l.Enabled(FromSlogLevel(lvl))
+}
+
+func (h *slogHandler) Handle(_ context.Context, r slog.Record) error {
+	h.l.RunningMutex.Lock()
+	running := h.l.Running
+	h.l.RunningMutex.Unlock()
+	if !running {
+		return fmt.Errorf("blog: logger has been shut down")
+	}
+
+	lvl := FromSlogLevel(r.Level)
+	overridden := false
+	if v := h.l.vmodule.Load(); v != nil {
+		threshold, ok := v.nameLevel(h.l.name)
+		if !ok {
+			threshold, ok = v.fileLevel(r.PC)
+		}
+		var allowed bool
+		if allowed, overridden = h.l.allows(lvl, threshold, ok); !allowed {
+			return nil
+		}
+	}
+	m := LogMessage{
+		Record: record.Record{
+			Time:    r.Time,
+			Level:   lvl,
+			Name:    h.l.name,
+			Message: r.Message,
+		},
+		overridden: overridden,
+	}
+	if m.Time.IsZero() {
+		m.Time = time.Now()
+	}
+	if h.l.location && r.PC != 0 && includeLocation(lvl) {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		if frame.File != "" {
+			m.Location = fmt.Sprintf("%s:%d", filepath.Base(frame.File), frame.Line)
+		}
+	}
+	if len(h.fields) > 0 || r.NumAttrs() > 0 {
+		m.Fields = make([]record.Field, 0, len(h.fields)+r.NumAttrs())
+		m.Fields = append(m.Fields, h.fields...)
+		r.Attrs(func(a slog.Attr) bool {
+			m.Fields = appendAttr(m.Fields, h.prefix, a)
+			return true
+		})
+	}
+	h.l.enqueue(m)
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.fields = make([]record.Field, 0, len(h.fields)+len(attrs))
+	h2.fields = append(h2.fields, h.fields...)
+	for _, a := range attrs {
+		h2.fields = appendAttr(h2.fields, h.prefix, a)
+	}
+	return &h2
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// appendAttr resolves a and appends it to fields, flattening groups into prefixed keys.
+// Empty attributes and empty groups are dropped, matching the slog.Handler contract.
+func appendAttr(fields []record.Field, prefix string, a slog.Attr) []record.Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			fields = appendAttr(fields, prefix, ga)
+		}
+		return fields
+	}
+	return append(fields, record.Field{Key: prefix + a.Key, Value: a.Value.Any()})
+}
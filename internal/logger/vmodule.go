@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/Data-Corruption/blog/v3/internal/config"
+	LogLevel "github.com/Data-Corruption/blog/v3/internal/level"
+)
+
+// vmodule is the form of config.VModule read when logging, from any goroutine. It is replaced, never
+// modified, when the overrides change, which also starts a fresh cache.
+type vmodule struct {
+	names   config.VModule    // rules matching logger names, checked first and without a caller lookup
+	files   config.VModule    // rules matching caller files
+	fileMin LogLevel.LogLevel // the least severe threshold among the file rules, NONE when there are none
+	sites   sync.Map          // pc -> siteLevel, so each call site is matched once. Bounded by the code.
+}
+
+type siteLevel struct {
+	level LogLevel.LogLevel
+	ok    bool // false when no rule matched
+}
+
+// newVModule returns the overrides for the given rules, nil when there are none.
+func newVModule(rules config.VModule) *vmodule {
+	if len(rules) == 0 {
+		return nil
+	}
+	v := &vmodule{fileMin: LogLevel.NONE}
+	for _, r := range rules {
+		if r.IsFile() {
+			v.files = append(v.files, r)
+			v.fileMin = min(v.fileMin, r.Level)
+		} else {
+			v.names = append(v.names, r)
+		}
+	}
+	return v
+}
+
+// nameLevel returns the threshold of the first rule matching the logger name, and whether one did.
+func (v *vmodule) nameLevel(name string) (LogLevel.LogLevel, bool) {
+	if len(v.names) == 0 {
+		return 0, false
+	}
+	return v.names.Level(name, "")
+}
+
+// fileLevel returns the threshold of the first rule matching the file of pc, and whether one did.
+// A pc of 0 matches nothing.
+func (v *vmodule) fileLevel(pc uintptr) (LogLevel.LogLevel, bool) {
+	if pc == 0 || len(v.files) == 0 {
+		return 0, false
+	}
+	if s, ok := v.sites.Load(pc); ok {
+		return s.(siteLevel).level, s.(siteLevel).ok
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	lvl, ok := v.files.Level("", frame.File)
+	v.sites.Store(pc, siteLevel{level: lvl, ok: ok})
+	return lvl, ok
+}
+
+// allows reports whether a message of the given level passes the override that matched it, or the
+// current level when none did, and whether an override decided.
+func (l *Logger) allows(lvl, threshold LogLevel.LogLevel, matched bool) (allowed, overridden bool) {
+	if matched {
+		return threshold.Allows(lvl), true
+	}
+	return LogLevel.LogLevel(l.level.Load()).Allows(lvl), false
+}
+
+// callerPC returns the program counter of the code calling the log function, 0 if unknown.
+func (l *Logger) callerPC() uintptr {
+	var pcs [1]uintptr
+	runtime.Callers(l.callerSkip+2, pcs[:]) // skip runtime.Callers and callerPC, callerSkip is relative to qM
+	return pcs[0]
+}
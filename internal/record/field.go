@@ -0,0 +1,55 @@
+package record
+
+import "time"
+
+// badKey is used as the key for values passed without a matching string key.
+const badKey = "!BADKEY"
+
+// Field is a single structured key/value pair attached to a log message.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Typed field constructors. These avoid the ambiguity of alternating key/value arguments.
+
+func Any(key string, value any) Field                { return Field{Key: key, Value: value} }
+func String(key, value string) Field                 { return Field{Key: key, Value: value} }
+func Int(key string, value int) Field                { return Field{Key: key, Value: value} }
+func Int64(key string, value int64) Field            { return Field{Key: key, Value: value} }
+func Uint64(key string, value uint64) Field          { return Field{Key: key, Value: value} }
+func Float64(key string, value float64) Field        { return Field{Key: key, Value: value} }
+func Bool(key string, value bool) Field              { return Field{Key: key, Value: value} }
+func Time(key string, value time.Time) Field         { return Field{Key: key, Value: value} }
+func Duration(key string, value time.Duration) Field { return Field{Key: key, Value: value} }
+
+// Err returns a Field with the key "error" holding the given error.
+func Err(err error) Field { return Field{Key: "error", Value: err} }
+
+// FromArgs converts a mix of Field values and alternating key/value pairs into a slice of fields.
+// Example: FromArgs(String("a", "b"), "user", 42) -> [{a b} {user 42}]
+// A value without a string key is stored under the key "!BADKEY" rather than being dropped.
+func FromArgs(args []any) []Field {
+	if len(args) == 0 {
+		return nil
+	}
+	fields := make([]Field, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		switch a := args[i].(type) {
+		case Field:
+			fields = append(fields, a)
+		case []Field:
+			fields = append(fields, a...)
+		case string:
+			if i+1 >= len(args) {
+				fields = append(fields, Field{Key: badKey, Value: a})
+			} else {
+				fields = append(fields, Field{Key: a, Value: args[i+1]})
+				i++
+			}
+		default:
+			fields = append(fields, Field{Key: badKey, Value: a})
+		}
+	}
+	return fields
+}
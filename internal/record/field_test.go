@@ -0,0 +1,31 @@
+package record
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFromArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []any
+		expected []Field
+	}{
+		{"Empty", nil, nil},
+		{"Key value pairs", []any{"a", 1, "b", "two"}, []Field{{"a", 1}, {"b", "two"}}},
+		{"Typed fields", []any{String("a", "x"), Int("b", 2)}, []Field{{"a", "x"}, {"b", 2}}},
+		{"Mixed", []any{"a", 1, Bool("b", true)}, []Field{{"a", 1}, {"b", true}}},
+		{"Field slice", []any{[]Field{{"a", 1}, {"b", 2}}}, []Field{{"a", 1}, {"b", 2}}},
+		{"Dangling key", []any{"a"}, []Field{{badKey, "a"}}},
+		{"Non-string key", []any{42, "a", 1}, []Field{{badKey, 42}, {"a", 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FromArgs(tt.args)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("FromArgs(%v) = %v; expected %v", tt.args, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package record
+
+import (
+	"time"
+
+	LogLevel "github.com/Data-Corruption/blog/v3/internal/level"
+)
+
+// Record is a single log message in its structured form, before it is encoded for output.
+type Record struct {
+	Time     time.Time
+	Level    LogLevel.LogLevel
+	Name     string // name of the logger that produced the record, e.g. "db.pool". Empty for the root logger.
+	Location string // e.g., "file.go:42". Empty when location is disabled or not captured.
+	Message  string
+	Fields   []Field
+}
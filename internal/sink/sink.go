@@ -0,0 +1,69 @@
+package sink
+
+import (
+	"io"
+
+	"github.com/Data-Corruption/blog/v3/internal/format"
+	LogLevel "github.com/Data-Corruption/blog/v3/internal/level"
+	"github.com/Data-Corruption/blog/v3/internal/record"
+)
+
+// Sink is an output for log records. The logger only calls a sink's methods from its own goroutine,
+// so implementations don't need to be thread-safe unless they are shared between loggers.
+type Sink interface {
+	// Write outputs a single record. The record must not be retained after Write returns.
+	Write(r *record.Record) error
+	// Flush writes out anything the sink has buffered.
+	Flush() error
+	// Close flushes the sink and releases its resources. It is called when the sink is removed
+	// from the logger or the logger shuts down.
+	Close() error
+}
+
+// writerSink is a Sink that encodes records straight to an io.Writer.
+type writerSink struct {
+	w         io.Writer
+	encoding  format.Encoding
+	layout    *format.Layout // for text, nil for the default layout
+	encodeBuf []byte
+}
+
+// NewWriter returns a Sink that writes each record to w with the given encoding.
+// The sink doesn't close w, so it is safe to use with os.Stdout and friends.
+func NewWriter(w io.Writer, enc format.Encoding) Sink {
+	return &writerSink{w: w, encoding: enc}
+}
+
+// NewWriterLayout is NewWriter with the given layout for text, nil for the default layout.
+func NewWriterLayout(w io.Writer, enc format.Encoding, layout *format.Layout) Sink {
+	return &writerSink{w: w, encoding: enc, layout: layout}
+}
+
+func (s *writerSink) Write(r *record.Record) error {
+	s.encodeBuf = s.encoding.AppendLayout(s.encodeBuf[:0], r, s.layout)
+	_, err := s.w.Write(s.encodeBuf)
+	return err
+}
+
+func (s *writerSink) Flush() error { return nil }
+
+func (s *writerSink) Close() error { return nil }
+
+// levelSink passes records at or above its level on to the wrapped sink.
+type levelSink struct {
+	Sink
+	level LogLevel.LogLevel
+}
+
+// WithLevel returns a Sink that only passes records allowed by lvl on to s, giving any sink its
+// own minimum level. The logger's own level still applies first.
+func WithLevel(s Sink, lvl LogLevel.LogLevel) Sink {
+	return &levelSink{Sink: s, level: lvl}
+}
+
+func (s *levelSink) Write(r *record.Record) error {
+	if !s.level.Allows(r.Level) {
+		return nil
+	}
+	return s.Sink.Write(r)
+}
@@ -7,10 +7,11 @@ func CopyIfNotNil[T any](dst, src *T) {
 	}
 }
 
-// SetDefaultIfNil sets *dst to src if *dst is nil.
+// SetDefaultIfNil sets *dst to a pointer to a copy of *src if *dst is nil.
+// The copy keeps later writes through *dst from changing the default itself.
 func SetDefaultIfNil[T any](dst **T, src *T) {
 	if *dst == nil && src != nil {
-		*dst = src
+		*dst = Ptr(*src)
 	}
 }
 
@@ -21,3 +22,8 @@ func Ternary[T any](condition bool, a, b T) T {
 	}
 	return b
 }
+
+// Ptr returns a pointer to a copy of v.
+func Ptr[T any](v T) *T {
+	return &v
+}
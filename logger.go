@@ -0,0 +1,407 @@
+package blog
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/Data-Corruption/blog/v3/internal/config"
+	"github.com/Data-Corruption/blog/v3/internal/format"
+	LogLevel "github.com/Data-Corruption/blog/v3/internal/level"
+	"github.com/Data-Corruption/blog/v3/internal/logger"
+	"github.com/Data-Corruption/blog/v3/internal/utils"
+)
+
+// baseLocationSkip is the number of stack frames between the internal logger's caller lookup and
+// the caller of a Logger method.
+const baseLocationSkip = 5
+
+// Options configures a Logger created with New. Zero values fall back to the defaults noted on each field.
+type Options struct {
+	DirectoryPath      string           // directory for log files. "." for the current working directory, "" to disable file logging and log to the console instead.
+	Level              Level            // the minimum level to log. Default is INFO, the zero value.
+	VModule            string           // per component or source file overrides of Level, e.g. "db=DEBUG,http/*=WARN", see SetVModule.
+	FileLevel          *Level           // the minimum level written to the log file, on top of Level. Default, nil, lets everything through.
+	ConsoleLevel       *Level           // the minimum level printed to the console, on top of Level. Default, nil, lets everything through.
+	IncludeLocation    bool             // when true, adds source file and line number to ERROR, DEBUG, TRACE and FATAL messages.
+	LocationSkip       int              // extra stack frames to skip when finding the location, for use behind wrapper functions.
+	EnableConsole      bool             // when true, enables logging to the console in addition to files. Without a DirectoryPath, or once file logging fails, the console is used regardless.
+	ConsoleWriter      io.Writer        // where console output goes, including the fallback when file logging is off or fails. Default is os.Stdout.
+	MsgChanSize        int              // buffer size of the message channel. Default is 255, negative means unbuffered.
+	Overflow           OverflowPolicy   // what happens to messages logged while the message channel is full. Default is OverflowBlock.
+	OverflowLevel      Level            // the threshold for OverflowDropBelow, e.g. WARN keeps ERROR and WARN messages.
+	MaxBufferSizeBytes int              // the maximum size of the write buffer before it is flushed. Default is 4 KB.
+	MaxFileSizeBytes   int              // the maximum size of the log file before it is rotated. Default is 1 GB.
+	RotationSchedule   RotationSchedule // rotates the log file at time boundaries too, e.g. RotateDaily(0, false). Default is disabled.
+	Retention          RetentionPolicy  // limits on the rotated log files kept. Default keeps everything.
+	Compress           bool             // when true, rotated log files are gzipped in the background.
+	Recovery           RecoveryPolicy   // retries file logging with backoff after a failure fell back to the console. Default never retries.
+	ExternalRotation   bool             // when true, blog never rotates the log file itself and reopens it on SIGHUP, for tools like logrotate.
+	FileNaming         FileNaming       // the names of the active and rotated log files. Default is latest.log and 2006-01-02_15-04-05.log.
+	FlushInterval      time.Duration    // the interval at which the write buffer is flushed. Default is 15 seconds, negative disables.
+	FileEncoding       Encoding         // the encoding of the log file. Default is TextEncoding.
+	ConsoleEncoding    Encoding         // the encoding of console output. Default is TextEncoding.
+	TextLayout         TextLayout       // the layout of text output to the file and console. Default is DefaultTextTemplate.
+	OnError            func(error)      // called with internal errors, see SetErrorHandler.
+	Sinks              []Sink           // additional outputs next to the file and console. Closed on Cleanup.
+}
+
+// Logger is an independent logger instance with its own goroutine, buffer and files.
+// The package level functions manage a single Logger for the common use case, use New when you
+// need several, e.g. one per subsystem or one per parallel test.
+type Logger struct {
+	l             *logger.Logger
+	consoleWriter io.Writer
+}
+
+// New creates a Logger with the given options and starts its goroutine.
+// Call Cleanup when done with it.
+//
+// Returns an error wrapping ErrInvalidPath if the directory path is invalid for any reason,
+// ErrInvalidVModule if the overrides can't be parsed, or ErrInvalidLayout for an invalid TextLayout.
+func New(opts Options) (*Logger, error) {
+	l := &Logger{consoleWriter: utils.Ternary[io.Writer](opts.ConsoleWriter != nil, opts.ConsoleWriter, os.Stdout)}
+	vmodule, err := config.ParseVModule(opts.VModule)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidVModule, err)
+	}
+	layout, err := format.NewLayout(opts.TextLayout)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidLayout, err)
+	}
+	cfg := &config.Config{
+		Level:            utils.Ptr(LogLevel.LogLevel(opts.Level)),
+		VModule:          &vmodule,
+		DirectoryPath:    utils.Ptr(opts.DirectoryPath),
+		FileEncoding:     utils.Ptr(opts.FileEncoding),
+		ConsoleEncoding:  utils.Ptr(opts.ConsoleEncoding),
+		TextLayout:       &layout,
+		ConsoleOut:       l.consoleLogger(opts.EnableConsole),
+		ConsoleWriter:    l.consoleWriter,
+		Sinks:            opts.Sinks,
+		OnError:          opts.OnError,
+		RotationSchedule: utils.Ptr(opts.RotationSchedule),
+		Retention:        utils.Ptr(opts.Retention),
+		Compress:         utils.Ptr(opts.Compress),
+		ExternalRotation: utils.Ptr(opts.ExternalRotation),
+		Recovery:         utils.Ptr(opts.Recovery),
+		Overflow:         &config.Overflow{Policy: opts.Overflow, Level: LogLevel.LogLevel(opts.OverflowLevel)},
+		FileNaming:       utils.Ptr(opts.FileNaming),
+	}
+	if opts.FileLevel != nil {
+		cfg.FileLevel = utils.Ptr(LogLevel.LogLevel(*opts.FileLevel))
+	}
+	if opts.ConsoleLevel != nil {
+		cfg.ConsoleLevel = utils.Ptr(LogLevel.LogLevel(*opts.ConsoleLevel))
+	}
+	if opts.MaxBufferSizeBytes > 0 {
+		cfg.MaxBufferSizeBytes = utils.Ptr(opts.MaxBufferSizeBytes)
+	}
+	if opts.MaxFileSizeBytes > 0 {
+		cfg.MaxFileSizeBytes = utils.Ptr(opts.MaxFileSizeBytes)
+	}
+	if opts.FlushInterval != 0 {
+		cfg.FlushInterval = utils.Ptr(max(opts.FlushInterval, 0))
+	}
+	chanSize := utils.Ternary(opts.MsgChanSize == 0, 255, max(opts.MsgChanSize, 0))
+	if l.l, err = logger.NewLogger(cfg, chanSize, baseLocationSkip+opts.LocationSkip, opts.IncludeLocation); err != nil {
+		return nil, err // wraps ErrInvalidPath
+	}
+	return l, nil
+}
+
+// With returns a child Logger that adds the given fields to every message. Children share their
+// parent's goroutine, buffer and files, so they are cheap to create.
+func (l *Logger) With(fields ...any) *Logger {
+	if l == nil || l.l == nil {
+		return l
+	}
+	return &Logger{l: l.l.With(fields...), consoleWriter: l.consoleWriter}
+}
+
+// Named returns a child Logger that stamps every message with a component name. Names nest with dots,
+// e.g. l.Named("db").Named("pool") logs as "db.pool". Children share their parent's goroutine, buffer and files.
+func (l *Logger) Named(name string) *Logger {
+	if l == nil || l.l == nil {
+		return l
+	}
+	return &Logger{l: l.l.Named(name), consoleWriter: l.consoleWriter}
+}
+
+// Cleanup writes out every message logged so far, flushes and closes the outputs, and stops the logger.
+// If timeout is 0, Cleanup blocks indefinitely. Otherwise messages not written in time are lost and
+// the returned error says how many. If the logger is too busy to start stopping in time, it keeps
+// running and the error says so, call Cleanup again. Calling it on a child stops the goroutine shared with its parent.
+func (l *Logger) Cleanup(timeout time.Duration) error {
+	if err := l.guard(); err != nil {
+		return err
+	}
+	return l.l.Shutdown(timeout)
+}
+
+// ==== Logging Functions ===
+
+func (l *Logger) Error(msg string, fields ...any) error {
+	return l.a(func() { l.l.Error(msg, fields...) })
+}
+func (l *Logger) Errorf(format string, args ...any) error {
+	return l.a(func() { l.l.Errorf(format, args...) })
+}
+func (l *Logger) Warn(msg string, fields ...any) error {
+	return l.a(func() { l.l.Warn(msg, fields...) })
+}
+func (l *Logger) Warnf(format string, args ...any) error {
+	return l.a(func() { l.l.Warnf(format, args...) })
+}
+func (l *Logger) Info(msg string, fields ...any) error {
+	return l.a(func() { l.l.Info(msg, fields...) })
+}
+func (l *Logger) Infof(format string, args ...any) error {
+	return l.a(func() { l.l.Infof(format, args...) })
+}
+func (l *Logger) Debug(msg string, fields ...any) error {
+	return l.a(func() { l.l.Debug(msg, fields...) })
+}
+func (l *Logger) Debugf(format string, args ...any) error {
+	return l.a(func() { l.l.Debugf(format, args...) })
+}
+func (l *Logger) Trace(msg string, fields ...any) error {
+	return l.a(func() { l.l.Trace(msg, fields...) })
+}
+func (l *Logger) Tracef(format string, args ...any) error {
+	return l.a(func() { l.l.Tracef(format, args...) })
+}
+
+// Log logs a message at the given level, e.g. one added with RegisterLevel. A FATAL message is only
+// logged, use Fatal to exit. NONE is only a threshold, logging with it returns ErrInvalidLogLevel.
+func (l *Logger) Log(level Level, msg string, fields ...any) error {
+	if LogLevel.LogLevel(level).IsThreshold() {
+		return ErrInvalidLogLevel
+	}
+	return l.a(func() { l.l.Log(LogLevel.LogLevel(level), msg, fields...) })
+}
+func (l *Logger) Logf(level Level, format string, args ...any) error {
+	if LogLevel.LogLevel(level).IsThreshold() {
+		return ErrInvalidLogLevel
+	}
+	return l.a(func() { l.l.Logf(LogLevel.LogLevel(level), format, args...) })
+}
+
+// Fatal logs a fatal message and exits with the given exit code.
+// This function will not return, it will exit the program as soon as the message is flushed or the
+// timeout passes, after running the exit hooks.
+func (l *Logger) Fatal(exitCode int, timeout time.Duration, msg string, fields ...any) error {
+	return l.a(func() { l.l.Fatal(exitCode, timeout, msg, fields...) })
+}
+
+// Fatalf logs a fatal message with a format string and exits with the given exit code.
+// This function will not return, it will exit the program as soon as the message is flushed or the
+// timeout passes, after running the exit hooks.
+func (l *Logger) Fatalf(exitCode int, timeout time.Duration, format string, args ...any) error {
+	return l.a(func() { l.l.Fatalf(exitCode, timeout, format, args...) })
+}
+
+// Enabled reports whether a message of the given level would currently be logged, or could be by a
+// VModule override. Use it to skip building expensive log arguments. Always false for an uninitialized
+// or shutdown Logger.
+func (l *Logger) Enabled(level Level) bool {
+	return l.guard() == nil && l.l.Enabled(LogLevel.LogLevel(level))
+}
+
+// SlogHandler returns a slog.Handler that writes through this Logger.
+func (l *Logger) SlogHandler() (slog.Handler, error) {
+	var h slog.Handler
+	return h, l.a(func() { h = l.l.SlogHandler() })
+}
+
+// SetLevel sets the log level.
+func (l *Logger) SetLevel(level Level) error {
+	lvl := LogLevel.LogLevel(level)
+	return l.a(func() { l.l.UpdateConfig(config.Config{Level: &lvl}) })
+}
+
+// SetVModule sets overrides of the level for some components or source files, as comma separated
+// pattern=LEVEL rules. Patterns are globs. One containing a "/" or ending in ".go" matches the caller's
+// file by its trailing path elements, e.g. "server.go" or "http/*", any other the whole logger name,
+// e.g. "db" or "db.*". A rule matching the logger name decides a message's level, or else the first
+// rule matching its file, the rest use SetLevel. Example: "db=DEBUG,http/*=WARN".
+// An empty spec removes the overrides. Returns an error wrapping ErrInvalidVModule if spec is invalid.
+func (l *Logger) SetVModule(spec string) error {
+	v, err := config.ParseVModule(spec)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidVModule, err)
+	}
+	return l.a(func() { l.l.UpdateConfig(config.Config{VModule: &v}) })
+}
+
+// SetConsole enables or disables console logging.
+func (l *Logger) SetConsole(enable bool) error {
+	return l.a(func() { l.l.UpdateConfig(config.Config{ConsoleOut: l.consoleLogger(enable)}) })
+}
+
+// SetConsoleLevel sets the minimum level printed to the console. It filters on top of SetLevel.
+func (l *Logger) SetConsoleLevel(level Level) error {
+	lvl := LogLevel.LogLevel(level)
+	return l.a(func() { l.l.UpdateConfig(config.Config{ConsoleLevel: &lvl}) })
+}
+
+// SetConsoleEncoding sets the encoding of console output.
+func (l *Logger) SetConsoleEncoding(enc Encoding) error {
+	return l.a(func() { l.l.UpdateConfig(config.Config{ConsoleEncoding: &enc}) })
+}
+
+// SetTextLayout sets the layout of text output to the file and console. Returns an error wrapping
+// ErrInvalidLayout if the layout is invalid.
+func (l *Logger) SetTextLayout(layout TextLayout) error {
+	compiled, err := format.NewLayout(layout)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidLayout, err)
+	}
+	return l.a(func() { l.l.UpdateConfig(config.Config{TextLayout: &compiled}) })
+}
+
+// ==== Buffer controls ====
+
+// Flush manually flushes the log write buffer.
+func (l *Logger) Flush() error { return l.a(func() { l.l.Flush() }) }
+
+// SyncFlush synchronously flushes the log write buffer and blocks until the flush is complete or the
+// timeout is reached. If timeout is 0, SyncFlush blocks indefinitely.
+func (l *Logger) SyncFlush(timeout time.Duration) error {
+	return l.a(func() { l.l.SyncFlush(timeout) })
+}
+
+// Reopen flushes and closes the log file, and reopens it at its path on the next write.
+func (l *Logger) Reopen() error { return l.a(func() { l.l.Reopen() }) }
+
+// SetMaxBufferSizeBytes sets the maximum size of the log write buffer.
+func (l *Logger) SetMaxBufferSizeBytes(size int) error {
+	return l.a(func() { l.l.UpdateConfig(config.Config{MaxBufferSizeBytes: &size}) })
+}
+
+// SetOverflowPolicy sets what happens to messages logged while the message channel is full.
+// Level is only used by OverflowDropBelow.
+func (l *Logger) SetOverflowPolicy(policy OverflowPolicy, level Level) error {
+	o := config.Overflow{Policy: policy, Level: LogLevel.LogLevel(level)}
+	return l.a(func() { l.l.UpdateConfig(config.Config{Overflow: &o}) })
+}
+
+// Dropped returns the number of messages dropped by the overflow policy so far.
+// Always 0 for an uninitialized Logger.
+func (l *Logger) Dropped() uint64 {
+	if l == nil || l.l == nil {
+		return 0
+	}
+	return l.l.Dropped()
+}
+
+// SetFlushInterval sets the interval at which the log write buffer is automatically flushed.
+// A value of 0 disables automatic flushing.
+func (l *Logger) SetFlushInterval(d time.Duration) error {
+	return l.a(func() { l.l.UpdateConfig(config.Config{FlushInterval: &d}) })
+}
+
+// ==== File controls ====
+
+// SetMaxFileSizeBytes sets the maximum size of the log file before it is rotated.
+func (l *Logger) SetMaxFileSizeBytes(size int) error {
+	return l.a(func() { l.l.UpdateConfig(config.Config{MaxFileSizeBytes: &size}) })
+}
+
+// SetRecovery sets the retry policy for file logging after a failure fell back to the console.
+func (l *Logger) SetRecovery(p RecoveryPolicy) error {
+	return l.a(func() { l.l.UpdateConfig(config.Config{Recovery: &p}) })
+}
+
+// SetDirectoryPath sets the directory path for the log files. To disable file logging, use an empty string.
+func (l *Logger) SetDirectoryPath(path string) error {
+	return l.a(func() { l.l.UpdateConfig(config.Config{DirectoryPath: &path}) })
+}
+
+// SetRotationSchedule sets when the log file is rotated based on time, in addition to its size.
+// Use the zero RotationSchedule to disable time based rotation.
+func (l *Logger) SetRotationSchedule(s RotationSchedule) error {
+	return l.a(func() { l.l.UpdateConfig(config.Config{RotationSchedule: &s}) })
+}
+
+// SetRetention sets the limits on the rotated log files kept and applies them right away.
+func (l *Logger) SetRetention(p RetentionPolicy) error {
+	return l.a(func() { l.l.UpdateConfig(config.Config{Retention: &p}) })
+}
+
+// SetCompression enables or disables gzip compression of rotated log files.
+func (l *Logger) SetCompression(enable bool) error {
+	return l.a(func() { l.l.UpdateConfig(config.Config{Compress: &enable}) })
+}
+
+// SetExternalRotation enables or disables external rotation. While enabled, blog never rotates the
+// log file itself and reopens it on SIGHUP.
+func (l *Logger) SetExternalRotation(enable bool) error {
+	return l.a(func() { l.l.UpdateConfig(config.Config{ExternalRotation: &enable}) })
+}
+
+// SetFileNaming sets the names of the active and rotated log files.
+// The current file is flushed under its old name before switching.
+func (l *Logger) SetFileNaming(n FileNaming) error {
+	return l.a(func() { l.l.UpdateConfig(config.Config{FileNaming: &n}) })
+}
+
+// SetFileEncoding sets the encoding of records written to the log file.
+func (l *Logger) SetFileEncoding(enc Encoding) error {
+	return l.a(func() { l.l.UpdateConfig(config.Config{FileEncoding: &enc}) })
+}
+
+// SetFileLevel sets the minimum level written to the log file. It filters on top of SetLevel.
+func (l *Logger) SetFileLevel(level Level) error {
+	lvl := LogLevel.LogLevel(level)
+	return l.a(func() { l.l.UpdateConfig(config.Config{FileLevel: &lvl}) })
+}
+
+// ==== Sink controls ====
+
+// SetSinks replaces the additional sinks with the given ones. Sinks no longer present are closed.
+// Call it with no arguments to remove all additional sinks. The file and console outputs are unaffected.
+func (l *Logger) SetSinks(sinks ...Sink) error {
+	sinks = append([]Sink{}, sinks...) // non-nil even when empty, nil would be ignored
+	return l.a(func() { l.l.UpdateConfig(config.Config{Sinks: sinks}) })
+}
+
+// ==== Error controls ====
+
+// SetErrorHandler sets a function called with the logger's internal errors. Nil removes it.
+func (l *Logger) SetErrorHandler(f func(error)) error {
+	if f == nil {
+		f = func(error) {} // nil would be ignored
+	}
+	return l.a(func() { l.l.UpdateConfig(config.Config{OnError: f}) })
+}
+
+// === helpers ===
+
+// consoleLogger returns the console logger for the given enabled state.
+func (l *Logger) consoleLogger(enable bool) *config.ConsoleLogger {
+	return &config.ConsoleLogger{L: utils.Ternary(enable, log.New(l.consoleWriter, "", 0), nil)}
+}
+
+// guard checks that the logger is initialized and not shutdown.
+func (l *Logger) guard() error {
+	if l == nil || l.l == nil {
+		return ErrUninitialized
+	}
+	l.l.RunningMutex.Lock()
+	running := l.l.Running
+	l.l.RunningMutex.Unlock()
+	return utils.Ternary(running, nil, ErrShutdown)
+}
+
+// a is a helper function for methods that don't return anything.
+func (l *Logger) a(f func()) error {
+	if err := l.guard(); err != nil {
+		return err
+	}
+	f()
+	return nil
+}